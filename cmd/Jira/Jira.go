@@ -1,19 +1,27 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"io/fs"
 	"log"
+	"net/mail"
 	"os"
 	"path"
 	"regexp"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
+	"unicode"
 
 	"9fans.net/go/acme"
 	"olowe.co/issues/jira"
+	"olowe.co/issues/jira/config"
 )
 
 func init() {
@@ -24,6 +32,24 @@ func init() {
 type awin struct {
 	*acme.Win
 	fsys fs.FS
+	// ctx is cancelled when the window is deleted,
+	// aborting any requests made on its behalf.
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func newAwin(win *acme.Win, fsys fs.FS) *awin {
+	ctx, cancel := context.WithCancel(context.Background())
+	if f, ok := fsys.(*jira.FS); ok {
+		fsys = f.WithContext(ctx)
+	}
+	return &awin{Win: win, fsys: fsys, ctx: ctx, cancel: cancel}
+}
+
+// loop handles the window's events until it is deleted.
+func (w *awin) loop() {
+	w.EventLoop(w)
+	w.cancel()
 }
 
 func (w *awin) name() string {
@@ -81,12 +107,23 @@ func (w *awin) Look(text string) bool {
 	}
 	win.Name(wname)
 	if path.Base(pathname) == "issue" {
+		win.Fprintf("tag", "Comment Put ")
+	} else if path.Base(pathname) == "thread" {
 		win.Fprintf("tag", "Comment ")
+	} else if w.authored(f) {
+		win.Fprintf("tag", "Put Delete ")
 	}
-	ww := &awin{win, w.fsys}
-	go ww.EventLoop(ww)
+	f.Close()
+	ww := newAwin(win, w.fsys)
+	go ww.loop()
 	go func() {
-		if err := ww.Get(f); err != nil {
+		// Load through the new window's filesystem,
+		// so that deleting it, not w, aborts the load.
+		f, err := ww.fsys.Open(pathname)
+		if err == nil {
+			err = ww.Get(f)
+		}
+		if err != nil && ww.ctx.Err() == nil {
 			w.Err(err.Error())
 		}
 		ww.Addr("#0")
@@ -109,6 +146,11 @@ func (w *awin) Execute(cmd string) bool {
 			return false
 		}
 		query := strings.Join(fields[1:], " ")
+		// The server understands more of JQL than ParseQuery,
+		// so send the query anyway.
+		if _, err := jira.ParseQuery(query); err != nil {
+			w.Err("warning: " + err.Error())
+		}
 		go newSearch(w.fsys, query)
 		return true
 	case "Comment":
@@ -123,10 +165,82 @@ func (w *awin) Execute(cmd string) bool {
 		dname := path.Dir(w.name())
 		win.Name(path.Join("/jira", dname, "new"))
 		win.Fprintf("tag", "Post ")
-		a := &awin{win, w.fsys}
-		go a.EventLoop(a)
+		a := newAwin(win, w.fsys)
+		go a.loop()
+		return true
+	case "Put":
+		var err error
+		if path.Base(w.name()) == "issue" {
+			err = w.putIssue()
+		} else if isComment(w.name()) {
+			err = w.putComment()
+		} else {
+			return false
+		}
+		if err != nil {
+			w.Errf("put %s: %v", w.name(), err)
+			return true
+		}
+		if err := w.Get(nil); err != nil {
+			w.Err(err.Error())
+		}
+		return true
+	case "Raw":
+		f, ok := w.fsys.(*jira.FS)
+		if !ok {
+			return false
+		}
+		// toggle between plain text and Jira text formatting.
+		g := *f
+		g.Raw = !f.Raw
+		w.fsys = &g
+		if err := w.Get(nil); err != nil {
+			w.Err(err.Error())
+		}
+		return true
+	case "Delete":
+		if !isComment(w.name()) {
+			return false
+		}
+		if err := w.deleteComment(); err != nil {
+			w.Errf("delete %s: %v", w.name(), err)
+			return true
+		}
+		w.Del(true)
+		return true
+	case "Transition":
+		if path.Base(w.name()) != "issue" {
+			return false
+		}
+		if err := w.transition(strings.Join(fields[1:], " ")); err != nil {
+			w.Errf("transition %s: %v", w.issueKey(), err)
+			return true
+		}
+		if err := w.Get(nil); err != nil {
+			w.Err(err.Error())
+		}
+		return true
+	case "New":
+		project := strings.Split(w.name(), "/")[0]
+		if len(fields) > 1 {
+			project = fields[1]
+		}
+		if project == "" {
+			w.Err("New: no project named")
+			return true
+		}
+		if err := newIssue(w.fsys, project); err != nil {
+			w.Errf("new issue: %v", err)
+		}
 		return true
 	case "Post":
+		if strings.Count(w.name(), "/") == 1 {
+			// new issue window, named PROJ/new
+			if err := w.postIssue(); err != nil {
+				w.Errf("post issue: %v", err)
+			}
+			return true
+		}
 		if err := w.postComment(); err != nil {
 			w.Errf("post comment: %s", err.Error())
 		}
@@ -143,6 +257,7 @@ func (w *awin) Get(f fs.File) error {
 		fname = "." // special name for the root file in io/fs
 	}
 	if f == nil {
+		w.invalidate()
 		var err error
 		f, err = w.fsys.Open(fname)
 		if err != nil {
@@ -194,10 +309,235 @@ func (w *awin) postComment() error {
 	if !ok {
 		return fmt.Errorf("cannot write comment with filesystem type %T", w.fsys)
 	}
+	jtf := string(body)
+	if !f.Raw {
+		jtf = jira.ToJTF(jtf)
+	}
+	defer f.Cache.Invalidate(w.issueKey())
+	return f.Client.PostCommentContext(w.ctx, w.issueKey(), strings.NewReader(jtf))
+}
+
+// putComment replaces the comment shown in the window with the window's contents.
+func (w *awin) putComment() error {
+	body, err := w.ReadAll("body")
+	if err != nil {
+		return fmt.Errorf("read body: %w", err)
+	}
+	f, ok := w.fsys.(*jira.FS)
+	if !ok {
+		return fmt.Errorf("cannot write comment with filesystem type %T", w.fsys)
+	}
+	msg, err := mail.ReadMessage(bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("parse comment: %w", err)
+	}
+	b, err := io.ReadAll(msg.Body)
+	if err != nil {
+		return fmt.Errorf("read comment body: %w", err)
+	}
+	jtf := string(b)
+	if !f.Raw {
+		jtf = jira.ToJTF(jtf)
+	}
+	defer f.Cache.Invalidate(w.issueKey())
+	return f.Client.UpdateCommentContext(w.ctx, w.issueKey(), path.Base(w.name()), strings.NewReader(jtf))
+}
+
+func (w *awin) deleteComment() error {
+	f, ok := w.fsys.(*jira.FS)
+	if !ok {
+		return fmt.Errorf("cannot delete comment with filesystem type %T", w.fsys)
+	}
+	defer f.Cache.Invalidate(w.issueKey())
+	return f.Client.DeleteCommentContext(w.ctx, w.issueKey(), path.Base(w.name()))
+}
+
+// invalidate removes the issue or project shown in the window from the cache,
+// so that it is next read from Jira.
+func (w *awin) invalidate() {
+	f, ok := w.fsys.(*jira.FS)
+	if !ok {
+		return
+	}
+	if key := w.issueKey(); key != "" {
+		f.Cache.Invalidate(key)
+		return
+	}
+	if project := strings.Split(w.name(), "/")[0]; project != "" {
+		f.Cache.Invalidate(project)
+	}
+}
+
+// isComment reports whether name is the name of a comment file, such as TEST/1/69.
+func isComment(name string) bool {
+	elems := strings.Split(name, "/")
+	if len(elems) != 3 {
+		return false
+	}
+	_, err := strconv.Atoi(elems[2])
+	return err == nil
+}
+
+var myself struct {
+	sync.Mutex
+	*jira.User
+}
+
+// authored reports whether f is a comment written by the authenticated user.
+func (w *awin) authored(f fs.File) bool {
+	stat, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	c, ok := stat.(*jira.Comment)
+	if !ok {
+		return false
+	}
+	fsys, ok := w.fsys.(*jira.FS)
+	if !ok {
+		return false
+	}
+	myself.Lock()
+	defer myself.Unlock()
+	if myself.User == nil {
+		me, err := fsys.Client.MyselfContext(w.ctx)
+		if err != nil {
+			w.Errf("find authenticated user: %v", err)
+			return false
+		}
+		myself.User = me
+	}
+	return sameUser(&c.Author, myself.User)
+}
+
+// sameUser reports whether a and b are the same user.
+// Users are identified by account ID on Jira Cloud and by key otherwise;
+// the username is compared only when neither is known.
+// Users without any identity are never the same.
+func sameUser(a, b *jira.User) bool {
+	switch {
+	case a.AccountID != "" || b.AccountID != "":
+		return a.AccountID == b.AccountID
+	case a.Key != "" || b.Key != "":
+		return a.Key == b.Key
+	}
+	return a.Name != "" && a.Name == b.Name
+}
+
+// transition moves the window's issue through its workflow.
+// If name is empty, the available transitions are listed instead.
+func (w *awin) transition(name string) error {
+	f, ok := w.fsys.(*jira.FS)
+	if !ok {
+		return fmt.Errorf("cannot transition issue with filesystem type %T", w.fsys)
+	}
+	if name != "" {
+		return f.Client.TransitionContext(w.ctx, w.issueKey(), name)
+	}
+	transitions, err := f.Client.TransitionsContext(w.ctx, w.issueKey())
+	if err != nil {
+		return err
+	}
+	buf := &strings.Builder{}
+	for _, t := range transitions {
+		fmt.Fprintf(buf, "Transition %s\t(to %s)\n", t.Name, t.To.Name)
+	}
+	w.Err(buf.String())
+	return nil
+}
+
+// issueKey returns the key of the issue shown in the window, such as TEST-1.
+func (w *awin) issueKey() string {
 	elems := strings.Split(w.name(), "/")
-	ikey := fmt.Sprintf("%s-%s", elems[0], elems[1])
-	jtf := toJTF(string(body))
-	return f.Client.PostComment(ikey, strings.NewReader(jtf))
+	if len(elems) < 2 {
+		return ""
+	}
+	return fmt.Sprintf("%s-%s", elems[0], elems[1])
+}
+
+const newIssueTemplate = `Subject: 
+Type: Task
+Assignee: 
+Labels: 
+
+`
+
+func newIssue(fsys fs.FS, project string) error {
+	win, err := acme.New()
+	if err != nil {
+		return err
+	}
+	win.Name(path.Join("/jira", project, "new"))
+	win.Fprintf("tag", "Post ")
+	win.Write("body", []byte(newIssueTemplate))
+	a := newAwin(win, fsys)
+	go a.loop()
+	return nil
+}
+
+// postIssue creates an issue from the contents of a new issue window
+// then renames the window to the created issue.
+func (w *awin) postIssue() error {
+	body, err := w.ReadAll("body")
+	if err != nil {
+		return fmt.Errorf("read body: %w", err)
+	}
+	f, ok := w.fsys.(*jira.FS)
+	if !ok {
+		return fmt.Errorf("cannot create issue with filesystem type %T", w.fsys)
+	}
+	msg, err := mail.ReadMessage(bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("parse issue: %w", err)
+	}
+	issue := &jira.Issue{
+		Project: jira.Project{Key: path.Dir(w.name())},
+		Summary: strings.TrimSpace(msg.Header.Get("Subject")),
+		Type:    jira.IssueType{Name: strings.TrimSpace(msg.Header.Get("Type"))},
+		Labels: strings.FieldsFunc(msg.Header.Get("Labels"), func(r rune) bool {
+			return r == ',' || unicode.IsSpace(r)
+		}),
+	}
+	if issue.Summary == "" {
+		return fmt.Errorf("empty subject")
+	}
+	if a := strings.TrimSpace(msg.Header.Get("Assignee")); a != "" {
+		u, err := f.Client.LookupUserContext(w.ctx, a)
+		if err != nil {
+			return fmt.Errorf("assignee: %w", err)
+		}
+		issue.Assignee = *u
+	}
+	desc, err := io.ReadAll(msg.Body)
+	if err != nil {
+		return fmt.Errorf("read description: %w", err)
+	}
+	issue.Description = string(desc)
+	if !f.Raw {
+		issue.Description = jira.ToJTF(issue.Description)
+	}
+
+	created, err := f.Client.CreateIssueContext(w.ctx, issue)
+	if err != nil {
+		return err
+	}
+	proj, num, _ := strings.Cut(created.Key, "-")
+	w.Name(path.Join("/jira", proj, num, "issue"))
+	w.Ctl("cleartag")
+	w.Fprintf("tag", "Comment Put ")
+	return w.Get(nil)
+}
+
+func (w *awin) putIssue() error {
+	body, err := w.ReadAll("body")
+	if err != nil {
+		return fmt.Errorf("read body: %w", err)
+	}
+	f, ok := w.fsys.(*jira.FS)
+	if !ok {
+		return fmt.Errorf("cannot write issue with filesystem type %T", w.fsys)
+	}
+	return f.WriteFile(w.name(), body)
 }
 
 func newSearch(fsys fs.FS, query string) {
@@ -211,16 +551,19 @@ func newSearch(fsys fs.FS, query string) {
 	f, ok := fsys.(*jira.FS)
 	if !ok {
 		win.Errf("cannot search with filesystem type %T", fsys)
+		return
 	}
+	w := newAwin(win, fsys)
+	go w.loop()
 	win.PrintTabbed("Search " + query + "\n\n")
-	issues, err := f.Client.SearchIssues(query)
+	issues, err := f.Client.SearchIssuesContext(w.ctx, query)
 	if err != nil {
-		win.Errf("search %q: %v", query, err)
+		if w.ctx.Err() == nil {
+			win.Errf("search %q: %v", query, err)
+		}
 		return
 	}
 	win.PrintTabbed(printIssues(issues))
-	w := &awin{win, fsys}
-	go w.EventLoop(w)
 }
 
 func printIssues(issues []jira.Issue) string {
@@ -232,55 +575,44 @@ func printIssues(issues []jira.Issue) string {
 	return buf.String()
 }
 
-const usage string = "usage: Jira [-d]"
+const usage string = "usage: Jira [-d] [-r] [-m dir] [-profile name] [config]"
 
-func readCreds(name string) (username, password string, err error) {
-	b, err := os.ReadFile(name)
-	if err != nil {
-		return "", "", err
-	}
-	u, p, found := strings.Cut(strings.TrimSpace(string(b)), ":")
-	if !found {
-		return "", "", fmt.Errorf("missing userpass field separator %q", ":")
-	}
-	return u, p, nil
-}
+// cacheTTL is how long issues are shown without checking for updates.
+// Executing Get in a window always reads its issue afresh.
+const cacheTTL = time.Minute
 
-var debug bool
-
-func init() {
-	flag.BoolVar(&debug, "d", false, "debug")
-	flag.Parse()
-}
+var debug = flag.Bool("d", false, "debug")
+var raw = flag.Bool("r", false, "show descriptions and comments in Jira text formatting")
+var profile = flag.String("profile", "", "connect using the configuration profile `name`")
+var mirror = flag.String("m", "", "read the mirror in `dir` instead of connecting to Jira")
 
 func main() {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		log.Fatalf("find user config dir: %v", err)
-	}
-	credPath := path.Join(home, ".config/atlassian/jira")
+	flag.Parse()
+	var confPath string
 	if len(flag.Args()) == 1 {
-		credPath = flag.Args()[0]
-	} else if len(flag.Args()) > 2 {
+		confPath = flag.Args()[0]
+	} else if len(flag.Args()) > 1 {
 		fmt.Fprintln(os.Stderr, usage)
 		os.Exit(2)
 	}
-	config, err := readConfig(credPath)
-	if err != nil {
-		log.Fatalf("read configuration: %v", err)
-	}
-
-	// srv := newFakeServer("testdata")
-	// defer srv.Close()
-
-	fsys := &jira.FS{
-		Client: &jira.Client{
-			Debug:    debug,
-			APIRoot:  config.BaseURL,
-			Username: config.Username,
-			Password: config.Password,
-		},
+	var client *jira.Client
+	var fields []string
+	if *mirror != "" {
+		var err error
+		client, err = jira.MirrorClient(*mirror)
+		if err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		prof, err := config.Load(confPath, *profile)
+		if err != nil {
+			log.Fatalf("read configuration: %v", err)
+		}
+		client = prof.Client()
+		fields = prof.Fields
 	}
+	client.Debug = *debug
+	fsys := &jira.FS{Client: client, Raw: *raw, Cache: jira.NewCache(cacheTTL), CustomFields: fields}
 
 	acme.AutoExit(true)
 	win, err := acme.New()
@@ -288,11 +620,11 @@ func main() {
 		log.Fatal(err)
 	}
 	win.Name("/jira/")
-	root := &awin{win, fsys}
+	root := newAwin(win, fsys)
 	root.Get(nil)
 	root.Addr("#0")
 	root.Ctl("dot=addr")
 	root.Ctl("show")
 	win.Ctl("clean")
-	root.EventLoop(root)
+	root.loop()
 }
@@ -0,0 +1,104 @@
+// Command jiraattach attaches files to a Jira issue.
+//
+// Its usage is:
+//
+//	jiraattach [ -n name ] [ -profile name ] [ -u url ] key file ...
+//
+// Each file is uploaded and attached to the issue key, such as TEST-1,
+// under its base name.
+// A file named "-" is read from the standard input.
+// The ID and name of each attachment are printed as it is made.
+//
+// The flags are:
+//
+//	-n name
+//		Name the attachment read from the standard input.
+//		The default is "stdin".
+//	-profile name
+//		Connect using the named profile from the configuration file.
+//		See package olowe.co/issues/jira/config for its format.
+//	-u url
+//		The URL of the Jira server, overriding that of the profile.
+//
+// # Examples
+//
+// Attach a log file and a screenshot to the issue SRE-1234:
+//
+//	jiraattach SRE-1234 /var/log/printer.log fire.png
+//
+// Attach the output of a command:
+//
+//	dmesg | jiraattach -n dmesg.txt SRE-1234 -
+//
+// Attachments may be read from the attachments directory of an issue
+// in the filesystem presented by Jira.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"olowe.co/issues/jira"
+	"olowe.co/issues/jira/config"
+)
+
+var apiRoot = flag.String("u", "", "base URL for the JIRA API, overriding the profile")
+var profile = flag.String("profile", "", "connect using the configuration profile `name`")
+var stdinName = flag.String("n", "stdin", "attach the standard input as `name`")
+
+const usage = "usage: jiraattach [-n name] [-profile name] [-u url] key file ..."
+
+func init() {
+	log.SetPrefix("jiraattach: ")
+	log.SetFlags(0)
+}
+
+func main() {
+	flag.Parse()
+	if flag.NArg() < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+	key := flag.Arg(0)
+
+	prof, err := config.Load("", *profile)
+	if err != nil {
+		log.Fatalf("read configuration: %v", err)
+	}
+	if *apiRoot != "" {
+		if err := prof.SetURL(*apiRoot); err != nil {
+			log.Fatalln("parse api url:", err)
+		}
+	}
+	client := prof.Client()
+
+	var failed bool
+	for _, name := range flag.Args()[1:] {
+		a, err := attach(client, key, name)
+		if err != nil {
+			log.Printf("attach %s: %v", name, err)
+			failed = true
+			continue
+		}
+		fmt.Printf("%s\t%s\n", a.ID, a.Filename)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// attach attaches the named file to the issue key.
+func attach(client *jira.Client, key, name string) (*jira.Attachment, error) {
+	if name == "-" {
+		return client.Attach(key, *stdinName, os.Stdin)
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return client.Attach(key, filepath.Base(name), f)
+}
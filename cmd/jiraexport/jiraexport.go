@@ -1,18 +1,44 @@
 // Command jiraexport prints the named Jira issues, and their comments,
-// in RFC 5322 mail message format (email).
+// as a mailbox in mboxrd format.
+// Each issue is the root of a thread of messages
+// with a Message-ID derived from the issue key;
+// each comment is a reply to the issue.
 //
 // Usage:
 //
-//	jiraexport [ -d duration ] issue...
+//	jiraexport [ -c ] [ -d duration ] [ -m dir ] [ -q query ] [ -profile name ] [ -s file ] [ -u url ] [ issue ... ]
 //
 // The options are:
 //
 //	-d duration
-//		Exclude any issues and comments unmodified since duration.
+//		Exclude any comments unmodified since duration.
 //		Duration may be given in the format accepted by time.ParseDuration.
 //		For example, 24h (24 hours). The default is 7 days.
 //	-c
 //		Only print comments, excluding the issue.
+//	-m dir
+//		Read issues from the mirror in dir, written by jirasync,
+//		instead of connecting to Jira.
+//		Queries given with -q are evaluated locally,
+//		so may use only the common fields, such as project and status,
+//		accepted by Query.Check in package olowe.co/issues/jira.
+//		Message IDs name the host localhost instead of the Jira server.
+//	-q query
+//		Export the issues matching the JQL query
+//		in addition to any issues named as arguments.
+//	-profile name
+//		Connect using the named profile from the configuration file.
+//		See package olowe.co/issues/jira/config for its format.
+//		Custom fields named in the profile are written as headers
+//		of each issue's message.
+//	-s file
+//		Export only activity not yet recorded in the state file,
+//		then record the time each exported issue was last updated.
+//		Issues not already recorded are exported in full, ignoring -d;
+//		recorded issues which have since been updated have only their
+//		new or edited comments exported.
+//	-u url
+//		The URL of the Jira server, overriding that of the profile.
 //
 // # Example
 //
@@ -20,156 +46,217 @@
 //
 //	jiraexport -d 24h SRE-1234 SRE-5678
 //
-// Archive the first 500 tickets of a project in a mbox file:
+// Archive every ticket in a project in a mbox file:
 //
-//	for i in `seq 1 500`
-//	do
-//		jiraexport TEST-$i >>issues.mbox
-//	done
+//	jiraexport -q 'project = TEST' -s issues.state >>issues.mbox
+//
+// Running the same command again, such as from cron,
+// appends only the new issues and comments.
+//
+// Replies to the exported messages can be posted back to Jira
+// with jiraimport.
 package main
 
 import (
-	"bytes"
 	"flag"
 	"fmt"
 	"io"
 	"io/fs"
 	"log"
 	"net/mail"
-	"net/url"
+	"net/textproto"
 	"os"
 	"path"
+	"strconv"
 	"strings"
 	"time"
 
 	"olowe.co/issues/jira"
+	"olowe.co/issues/jira/config"
 )
 
-func readJiraAuth() (user, pass string, err error) {
-	confDir, err := os.UserConfigDir()
-	if err != nil {
-		return "", "", err
-	}
-	b, err := os.ReadFile(path.Join(confDir, "atlassian/jira"))
-	if err != nil {
-		return "", "", err
-	}
-	b = bytes.TrimSpace(b)
-	u, p, ok := strings.Cut(string(b), ":")
-	if !ok {
-		return "", "", fmt.Errorf(`missing ":" between username and password`)
-	}
-	return u, p, nil
-}
-
-func copyMessage(w io.Writer, msg *mail.Message) (n int, err error) {
-	for k, v := range msg.Header {
-		for i := range v {
-			nn, err := fmt.Fprintf(w, "%s: %s\n", k, v[i])
-			n += nn
-			if err != nil {
-				return n, fmt.Errorf("write header field %s: %w", k, err)
-			}
-		}
-	}
-	nnn, err := fmt.Fprintln(w)
-	n += nnn
-	if err != nil {
-		return n, err
-	}
-	nn, err := io.Copy(w, msg.Body)
-	return n + int(nn), err
-}
-
-const usage string = "jiraexport [-d duration] [-u url] issue [...]"
+const usage string = "jiraexport [-c] [-d duration] [-m dir] [-q query] [-profile name] [-s file] [-u url] [issue ...]"
 
 var since = flag.Duration("d", 7*24*time.Hour, "exclude activity older than this duration")
-var apiRoot = flag.String("u", "http://[::1]:8080", "base URL for the JIRA API")
+var apiRoot = flag.String("u", "", "base URL for the JIRA API, overriding the profile")
+var profile = flag.String("profile", "", "connect using the configuration profile `name`")
 var onlyComments = flag.Bool("c", false, "only print comments")
+var query = flag.String("q", "", "export issues matching this JQL query")
+var mirror = flag.String("m", "", "read the mirror in `dir` instead of connecting to Jira")
+var stateFile = flag.String("s", "", "record and read last exported update times in `file`")
 
 func init() {
 	log.SetFlags(0)
 	log.SetPrefix("")
-	flag.Parse()
 }
 
 func main() {
-	if len(flag.Args()) == 0 {
+	flag.Parse()
+	if len(flag.Args()) == 0 && *query == "" {
 		log.Fatal(usage)
 	}
 
-	user, pass, err := readJiraAuth()
+	fsys, err := filesystem()
 	if err != nil {
-		log.Fatalf("read jira auth credentials: %v", err)
+		log.Fatal(err)
 	}
-	u, err := url.Parse(*apiRoot)
-	if err != nil {
-		log.Fatalln("parse api url:", err)
+
+	keys := flag.Args()
+	if *query != "" {
+		iter := fsys.Client.Search(*query)
+		for iter.Next() {
+			for _, issue := range iter.Page() {
+				keys = append(keys, issue.Key)
+			}
+		}
+		if err := iter.Err(); err != nil {
+			log.Fatalf("search %q: %v", *query, err)
+		}
 	}
-	u.Path = path.Join(u.Path, "rest/api/2")
-	jclient := &jira.Client{
-		APIRoot:  u,
-		Username: user,
-		Password: pass,
-		Debug:    false,
+
+	var st state
+	if *stateFile != "" {
+		st, err = readState(*stateFile)
+		if err != nil {
+			log.Fatalf("read state: %v", err)
+		}
 	}
-	fsys := &jira.FS{Client: jclient}
 
-	for _, arg := range flag.Args() {
-		proj, num, ok := strings.Cut(arg, "-")
-		if !ok {
-			log.Println("bad issue name: missing - separator")
-			continue
+	exportIssues(os.Stdout, fsys, keys, st, time.Now().Add(-*since), !*onlyComments)
+
+	if st != nil {
+		if err := st.write(*stateFile); err != nil {
+			log.Fatalf("write state: %v", err)
 		}
-		dir := path.Join(proj, num)
-		issue, err := fsys.Open(path.Join(dir, "issue"))
-		if err != nil {
-			log.Println(err)
+	}
+}
+
+// exportIssues writes the issues with the given keys to w
+// with their comments modified since cutoff.
+// If st is not nil, only activity not recorded in st is written,
+// and the time each issue was last updated is recorded in st.
+// Errors are logged and the issue skipped.
+func exportIssues(w io.Writer, fsys *jira.FS, keys []string, st state, cutoff time.Time, withIssue bool) {
+	for _, key := range keys {
+		cutoff, withIssue := cutoff, withIssue
+		var updated time.Time
+		if st != nil {
+			info, err := fs.Stat(fsys, strings.Replace(key, "-", "/", 1))
+			if err != nil {
+				log.Printf("export %s: %v", key, err)
+				continue
+			}
+			updated = info.ModTime()
+			last, seen := st[key]
+			if seen && !updated.After(last) {
+				continue // nothing new
+			}
+			// Unseen issues are exported in full.
+			cutoff = last
+			withIssue = withIssue && !seen
+		}
+		if err := export(w, fsys, fsys.Client.APIRoot.Hostname(), key, cutoff, withIssue); err != nil {
+			log.Printf("export %s: %v", key, err)
 			continue
 		}
-		msg, err := mail.ReadMessage(issue)
+		if st != nil {
+			st[key] = updated
+		}
+	}
+}
+
+// filesystem returns the issues in the mirror named by -m,
+// or those on the Jira server from the configuration profile
+// with the profile's custom fields.
+func filesystem() (*jira.FS, error) {
+	if *mirror != "" {
+		client, err := jira.MirrorClient(*mirror)
 		if err != nil {
-			log.Println("read issue:", err)
-			issue.Close()
-			continue
+			return nil, err
 		}
-		subject := msg.Header.Get("Subject")
-		if !*onlyComments {
-			if _, err := copyMessage(os.Stdout, msg); err != nil {
-				log.Println("print issue:", err)
-			}
+		return &jira.FS{Client: client}, nil
+	}
+	prof, err := config.Load("", *profile)
+	if err != nil {
+		return nil, fmt.Errorf("read configuration: %w", err)
+	}
+	if *apiRoot != "" {
+		if err := prof.SetURL(*apiRoot); err != nil {
+			return nil, fmt.Errorf("parse api url: %w", err)
+		}
+	}
+	return &jira.FS{Client: prof.Client(), CustomFields: prof.Fields}, nil
+}
+
+// export writes the issue with the given key, and its comments,
+// as a thread of messages in mboxrd format.
+// The issue is the root of the thread; each comment replies to it.
+// Only comments modified after since are written.
+// The issue itself is written only if withIssue is true.
+func export(w io.Writer, fsys fs.FS, host, key string, since time.Time, withIssue bool) error {
+	proj, num, ok := strings.Cut(key, "-")
+	if !ok {
+		return fmt.Errorf("bad issue name: missing - separator")
+	}
+	dir := path.Join(proj, num)
+	issue, err := fsys.Open(path.Join(dir, "issue"))
+	if err != nil {
+		return err
+	}
+	defer issue.Close()
+	msg, err := mail.ReadMessage(issue)
+	if err != nil {
+		return fmt.Errorf("read issue: %w", err)
+	}
+	subject := msg.Header.Get("Subject")
+	root := messageID(key, "", host)
+	if withIssue {
+		textproto.MIMEHeader(msg.Header).Set("Message-ID", root)
+		if err := writeMessage(w, msg.Header, msg.Body); err != nil {
+			return fmt.Errorf("write issue: %w", err)
 		}
-		issue.Close()
+	}
 
-		dents, err := fs.ReadDir(fsys, dir)
+	dents, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return err
+	}
+	for _, d := range dents {
+		if _, err := strconv.Atoi(d.Name()); err != nil {
+			continue // not a comment
+		}
+		info, err := d.Info()
 		if err != nil {
 			log.Println(err)
 			continue
 		}
-		for _, d := range dents {
-			if d.Name() == "issue" {
-				continue // already done
-			}
-			info, err := d.Info()
-			if err != nil {
-				log.Println(err)
-				continue
-			}
-			if time.Since(info.ModTime()) >= *since {
-				continue
-			}
-			f, err := fsys.Open(path.Join(dir, d.Name()))
-			if err != nil {
-				log.Println(err)
-				continue
-			}
-			fmt.Println()
-			fmt.Println("From nobody", info.ModTime().Format(time.ANSIC))
-			fmt.Println("Subject:", subject)
-			if _, err := io.Copy(os.Stdout, f); err != nil {
-				log.Println(err)
-			}
-			f.Close()
+		if !info.ModTime().After(since) {
+			continue
 		}
+		if err := exportComment(w, fsys, host, key, d.Name(), subject); err != nil {
+			log.Printf("export comment %s: %v", d.Name(), err)
+		}
+	}
+	return nil
+}
+
+// exportComment writes the comment id of the named issue
+// as a reply to the issue's message.
+func exportComment(w io.Writer, fsys fs.FS, host, key, id, subject string) error {
+	f, err := fsys.Open(path.Join(strings.Replace(key, "-", "/", 1), id))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	msg, err := mail.ReadMessage(f)
+	if err != nil {
+		return err
 	}
+	parent := messageID(key, "", host)
+	h := textproto.MIMEHeader(msg.Header)
+	h.Set("Subject", "Re: "+subject)
+	h.Set("Message-ID", messageID(key, id, host))
+	h.Set("In-Reply-To", parent)
+	h.Set("References", parent)
+	return writeMessage(w, msg.Header, msg.Body)
 }
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"olowe.co/issues/jira"
+	"olowe.co/issues/jira/jiratest"
+)
+
+func TestExport(t *testing.T) {
+	srv := jiratest.NewServer()
+	defer srv.Close()
+	key := srv.AddIssue("TEST", map[string]any{"summary": "Printer on fire", "description": "Help!"})
+	ann := jira.User{Name: "ann", DisplayName: "Ann"}
+	srv.AddComment(key, ann, "Have you tried turning it off and on again?")
+	fsys := &jira.FS{Client: srv.Client()}
+
+	var buf strings.Builder
+	if err := export(&buf, fsys, "jira.example.com", key, time.Time{}, true); err != nil {
+		t.Fatal(err)
+	}
+	r := splitMbox(t, buf.String())
+	if len(r) != 2 {
+		t.Fatalf("exported %d messages, want issue and 1 comment", len(r))
+	}
+	issue, comment := r[0], r[1]
+	if got := issue.Header.Get("Message-ID"); got != "<TEST-1@jira.example.com>" {
+		t.Errorf("issue has Message-ID %s", got)
+	}
+	if got := comment.Header.Get("In-Reply-To"); got != "<TEST-1@jira.example.com>" {
+		t.Errorf("comment has In-Reply-To %s", got)
+	}
+	if got := comment.Header.Get("Subject"); got != "Re: Printer on fire" {
+		t.Errorf("comment has Subject %q", got)
+	}
+
+	// Only comments are exported when requested,
+	// and none are newer than now.
+	buf.Reset()
+	if err := export(&buf, fsys, "jira.example.com", key, time.Now().Add(time.Hour), false); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() > 0 {
+		t.Errorf("exported old activity:\n%s", buf.String())
+	}
+}
+
+// splitMbox splits an mbox into its messages.
+func splitMbox(t *testing.T, mbox string) []*mail.Message {
+	t.Helper()
+	var msgs []*mail.Message
+	for _, s := range strings.Split(mbox, "\nFrom nobody ") {
+		_, s, _ = strings.Cut(s, "\n") // From line
+		msg, err := mail.ReadMessage(strings.NewReader(s))
+		if err != nil {
+			t.Fatalf("read exported message: %v", err)
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs
+}
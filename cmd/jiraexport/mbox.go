@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/mail"
+	"net/textproto"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// headerOrder is the order in which well-known header fields are written.
+// Any other fields follow in lexical order.
+var headerOrder = []string{
+	"From",
+	"Date",
+	"Subject",
+	"Message-Id",
+	"In-Reply-To",
+	"References",
+}
+
+// spelling holds the conventional spelling of header field names
+// which differ from their canonical MIME form.
+var spelling = map[string]string{
+	"Message-Id": "Message-ID",
+}
+
+var fromLine = regexp.MustCompile(`^>*From `)
+
+// writeMessage writes a message to w in mboxrd format:
+// a "From " separator line, the header fields in a deterministic order,
+// then the body with any lines beginning with "From " (after any number of ">")
+// quoted by an extra ">".
+// The message is terminated by an empty line.
+func writeMessage(w io.Writer, header mail.Header, body io.Reader) error {
+	date, err := header.Date()
+	if err != nil {
+		date = time.Unix(0, 0)
+	}
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "From nobody %s\n", date.UTC().Format(time.ANSIC))
+
+	keys := make([]string, 0, len(header))
+	for k := range header {
+		keys = append(keys, textproto.CanonicalMIMEHeaderKey(k))
+	}
+	slices.SortFunc(keys, func(a, b string) int {
+		i, j := slices.Index(headerOrder, a), slices.Index(headerOrder, b)
+		switch {
+		case i >= 0 && j >= 0:
+			return i - j
+		case i >= 0:
+			return -1
+		case j >= 0:
+			return 1
+		}
+		return strings.Compare(a, b)
+	})
+	keys = slices.Compact(keys)
+	for _, k := range keys {
+		name := k
+		if s, ok := spelling[k]; ok {
+			name = s
+		}
+		for _, v := range textproto.MIMEHeader(header).Values(k) {
+			fmt.Fprintf(bw, "%s: %s\n", name, v)
+		}
+	}
+	fmt.Fprintln(bw)
+
+	br := bufio.NewReader(body)
+	for {
+		line, err := br.ReadString('\n')
+		if line != "" {
+			if fromLine.MatchString(line) {
+				bw.WriteString(">")
+			}
+			bw.WriteString(strings.TrimSuffix(line, "\n") + "\n")
+		}
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("read body: %w", err)
+		}
+	}
+	fmt.Fprintln(bw)
+	return bw.Flush()
+}
+
+// messageID returns the stable Message-ID of the issue with the given key.
+// If comment is not empty, the ID of that comment on the issue is returned instead.
+// Host is the host name of the Jira server.
+func messageID(key, comment, host string) string {
+	if strings.Contains(host, ":") {
+		// an IPv6 address; use a domain literal.
+		host = "[" + host + "]"
+	}
+	if comment == "" {
+		return fmt.Sprintf("<%s@%s>", key, host)
+	}
+	return fmt.Sprintf("<%s.%s@%s>", key, comment, host)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"net/mail"
+	"strings"
+	"testing"
+)
+
+func TestWriteMessage(t *testing.T) {
+	header := mail.Header{
+		"Status":     {"Open"},
+		"Subject":    {"Printer on fire"},
+		"Message-Id": {"<TEST-1@jira.example.com>"},
+		"Date":       {"Mon, 01 Jan 2024 09:00:00 +0000"},
+		"Assignee":   {"Fred <fred@example.com>"},
+		"From":       {"Ann <ann@example.com>"},
+	}
+	body := "From the top:\n>From here\nnot From here\nno trailing newline"
+	want := `From nobody Mon Jan  1 09:00:00 2024
+From: Ann <ann@example.com>
+Date: Mon, 01 Jan 2024 09:00:00 +0000
+Subject: Printer on fire
+Message-ID: <TEST-1@jira.example.com>
+Assignee: Fred <fred@example.com>
+Status: Open
+
+>From the top:
+>>From here
+not From here
+no trailing newline
+
+`
+	buf := &strings.Builder{}
+	if err := writeMessage(buf, header, strings.NewReader(body)); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestMessageID(t *testing.T) {
+	tests := []struct {
+		key, comment, host string
+		want               string
+	}{
+		{"TEST-1", "", "jira.example.com", "<TEST-1@jira.example.com>"},
+		{"TEST-1", "69", "jira.example.com", "<TEST-1.69@jira.example.com>"},
+		{"TEST-1", "", "::1", "<TEST-1@[::1]>"},
+	}
+	for _, tt := range tests {
+		if got := messageID(tt.key, tt.comment, tt.host); got != tt.want {
+			t.Errorf("messageID(%q, %q, %q) = %q, want %q", tt.key, tt.comment, tt.host, got, tt.want)
+		}
+	}
+}
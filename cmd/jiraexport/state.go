@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// state records the time each exported issue was last updated.
+// It is stored as lines of an issue key and RFC 3339 timestamp
+// separated by whitespace, such as:
+//
+//	TEST-1 2024-01-01T09:00:00.123Z
+//
+// Fractions of a second are kept, as Jira records times to the millisecond;
+// otherwise an issue would seem updated since it was last exported.
+type state map[string]time.Time
+
+// readState reads the state file at name.
+// A missing file is treated as an empty state.
+func readState(name string) (state, error) {
+	st := make(state)
+	f, err := os.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return st, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, ts, ok := strings.Cut(line, " ")
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: missing timestamp", name, n)
+		}
+		t, err := time.Parse(time.RFC3339, strings.TrimSpace(ts))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", name, n, err)
+		}
+		st[key] = t
+	}
+	return st, sc.Err()
+}
+
+// write replaces the state file at name.
+// The file is written in full before it replaces any existing file,
+// so an interrupted write leaves the previous state intact.
+func (st state) write(name string) error {
+	keys := make([]string, 0, len(st))
+	for k := range st {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	f, err := os.CreateTemp(filepath.Dir(name), filepath.Base(name)+".*")
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	for _, k := range keys {
+		fmt.Fprintln(w, k, st[k].UTC().Format(time.RFC3339Nano))
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), name)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"olowe.co/issues/jira"
+	"olowe.co/issues/jira/jiratest"
+)
+
+func TestState(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "state")
+	st, err := readState(name)
+	if err != nil {
+		t.Fatalf("read missing state file: %v", err)
+	}
+	if len(st) > 0 {
+		t.Fatalf("state from missing file has %d entries", len(st))
+	}
+
+	want := state{
+		"TEST-1": time.Date(2024, 1, 1, 9, 0, 0, 123e6, time.UTC),
+		"TEST-2": time.Date(2024, 2, 1, 9, 0, 0, 0, time.UTC),
+	}
+	if err := want.write(name); err != nil {
+		t.Fatal(err)
+	}
+	got, err := readState(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("read %d entries, want %d", len(got), len(want))
+	}
+	for k, v := range want {
+		if !got[k].Equal(v) {
+			t.Errorf("%s: got %s, want %s", k, got[k], v)
+		}
+	}
+}
+
+func TestStateUnchanged(t *testing.T) {
+	// Jira records times to the millisecond,
+	// so make sure they are not whole seconds.
+	for time.Now().Nanosecond() < 100*int(time.Millisecond) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	srv := jiratest.NewServer()
+	defer srv.Close()
+	key := srv.AddIssue("TEST", map[string]any{"summary": "Printer on fire"})
+	srv.AddComment(key, jira.User{Name: "ann", DisplayName: "Ann"}, "Turn it off and on again.")
+	fsys := &jira.FS{Client: srv.Client()}
+	name := filepath.Join(t.TempDir(), "state")
+
+	var buf strings.Builder
+	for i := 0; i < 2; i++ {
+		st, err := readState(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf.Reset()
+		exportIssues(&buf, fsys, []string{key}, st, time.Time{}, true)
+		if err := st.write(name); err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 && buf.Len() == 0 {
+			t.Fatal("first run exported nothing")
+		}
+	}
+	if buf.Len() > 0 {
+		t.Errorf("second run exported recorded activity:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,210 @@
+// Command jiraimport posts replies to messages written by jiraexport
+// as comments on the corresponding Jira issues.
+// Together the two commands let people follow and take part
+// in discussion on issues entirely from their mail client.
+//
+// Usage:
+//
+//	jiraimport [ -n ] [ -from address ] [ -profile name ] [ -u url ] [ maildir ... ]
+//
+// With no arguments, a single message is read from the standard input.
+// Otherwise each new message in the named maildirs is posted
+// then marked as seen by moving it to the maildir's cur directory.
+//
+// The issue is found from the message IDs in the In-Reply-To and References
+// header fields, which jiraexport derives from issue keys,
+// such as <TEST-1@jira.example.com> or <TEST-1.10001@jira.example.com>.
+// Failing that, the first issue key mentioned in the Subject is used.
+//
+// Only the text/plain content of the message is posted.
+// It must be in UTF-8, US-ASCII, ISO 8859-1 or Windows-1252;
+// parts in other character sets are skipped.
+// Quoted text, its attribution line, and any signature are removed,
+// then the remaining text is converted to Jira text formatting
+// in the same way as comments posted from Jira.
+//
+// Every comment is posted by the user of the configured profile,
+// whoever sent the message, so anyone able to deliver mail
+// to the maildirs may comment as that user.
+// To show who wrote it, each comment begins with a line naming
+// the sender from the message's From field.
+// That field is set by the sender and is easily forged;
+// the -from flag limits imports to messages from known addresses,
+// but should be relied on only where the mail system
+// authenticates senders before delivery.
+//
+// The options are:
+//
+//	-n
+//		Print the issue key and comment text instead of posting.
+//	-from address
+//		Import only messages sent from address.
+//		The flag may be repeated to allow several addresses.
+//		By default messages from any sender are imported.
+//	-profile name
+//		Connect using the named profile from the configuration file.
+//		See package olowe.co/issues/jira/config for its format.
+//	-u url
+//		The URL of the Jira server, overriding that of the profile.
+//
+// # Example
+//
+// Post replies filed in a maildir by a mail filter:
+//
+//	jiraimport $HOME/mail/jira-replies
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"olowe.co/issues/jira"
+	"olowe.co/issues/jira/config"
+)
+
+const usage string = "jiraimport [-n] [-from address] [-profile name] [-u url] [maildir ...]"
+
+var apiRoot = flag.String("u", "", "base URL for the JIRA API, overriding the profile")
+var profile = flag.String("profile", "", "connect using the configuration profile `name`")
+var dryRun = flag.Bool("n", false, "print comments instead of posting them")
+
+// allowedSenders holds the addresses given by the -from flag.
+var allowedSenders []string
+
+func init() {
+	flag.Func("from", "import only messages sent from `address` (repeatable)", func(s string) error {
+		addr, err := mail.ParseAddress(s)
+		if err != nil {
+			return err
+		}
+		allowedSenders = append(allowedSenders, addr.Address)
+		return nil
+	})
+}
+
+func init() {
+	log.SetFlags(0)
+	log.SetPrefix("jiraimport: ")
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage:", usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	var client *jira.Client
+	if !*dryRun {
+		prof, err := config.Load("", *profile)
+		if err != nil {
+			log.Fatalf("read configuration: %v", err)
+		}
+		if *apiRoot != "" {
+			if err := prof.SetURL(*apiRoot); err != nil {
+				log.Fatalln("parse api url:", err)
+			}
+		}
+		client = prof.Client()
+	}
+
+	if len(flag.Args()) == 0 {
+		if err := importMessage(client, os.Stdin); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	var failed bool
+	for _, dir := range flag.Args() {
+		if err := importMaildir(client, dir); err != nil {
+			log.Println(err)
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// importMaildir imports each message in the new directory of the maildir dir.
+// Imported messages are moved to the cur directory and flagged as seen,
+// so they are not imported again.
+// Messages which cannot be imported are left in place.
+func importMaildir(client *jira.Client, dir string) error {
+	dents, err := os.ReadDir(filepath.Join(dir, "new"))
+	if err != nil {
+		return err
+	}
+	var failed int
+	for _, d := range dents {
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".") {
+			continue
+		}
+		name := filepath.Join(dir, "new", d.Name())
+		if err := importFile(client, name); err != nil {
+			log.Printf("%s: %v", name, err)
+			failed++
+			continue
+		}
+		if client == nil {
+			continue // dry run; leave the message unseen
+		}
+		if err := os.Rename(name, filepath.Join(dir, "cur", d.Name()+":2,S")); err != nil {
+			log.Printf("mark %s seen: %v", name, err)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%s: %d messages not imported", dir, failed)
+	}
+	return nil
+}
+
+func importFile(client *jira.Client, name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return importMessage(client, f)
+}
+
+// importMessage reads a message from r and posts it as a comment
+// on the issue it replies to.
+// If client is nil, the comment is printed to the standard output instead.
+func importMessage(client *jira.Client, r io.Reader) error {
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return fmt.Errorf("read message: %w", err)
+	}
+	from, err := sender(msg.Header, allowedSenders)
+	if err != nil {
+		return err
+	}
+	key, err := issueKey(msg.Header)
+	if err != nil {
+		return err
+	}
+	body, err := textBody(msg)
+	if err != nil {
+		return fmt.Errorf("read body: %w", err)
+	}
+	text := stripQuoted(body)
+	if text == "" {
+		return fmt.Errorf("no reply text after removing quoted text")
+	}
+	comment := attribution(from) + jira.ToJTF(text)
+	if client == nil {
+		fmt.Printf("%s\n%s\n\n", key, comment)
+		return nil
+	}
+	if err := client.PostComment(key, strings.NewReader(comment)); err != nil {
+		return fmt.Errorf("post comment to %s: %w", key, err)
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"cmp"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+var issueKeyExp = regexp.MustCompile(`[A-Z][A-Z0-9]+-[0-9]+`)
+
+var messageIDExp = regexp.MustCompile(`^<([A-Z][A-Z0-9]+-[0-9]+)(\.[0-9]+)?@`)
+
+// issueKey returns the key of the issue the message replies to.
+// Message IDs written by jiraexport, such as <TEST-1@example.com>
+// or <TEST-1.69@example.com>, are searched for in the
+// In-Reply-To and References fields.
+// Failing that, the first issue key in the Subject is used.
+func issueKey(header mail.Header) (string, error) {
+	for _, k := range []string{"In-Reply-To", "References"} {
+		for _, id := range strings.Fields(header.Get(k)) {
+			if m := messageIDExp.FindStringSubmatch(id); m != nil {
+				return m[1], nil
+			}
+		}
+	}
+	if key := issueKeyExp.FindString(header.Get("Subject")); key != "" {
+		return key, nil
+	}
+	return "", fmt.Errorf("no issue key found in message headers")
+}
+
+// sender returns the address in the From field of the message header.
+// If allowed is not empty, it is an error if the address is not one of them.
+func sender(header mail.Header, allowed []string) (*mail.Address, error) {
+	from, err := header.AddressList("From")
+	if err != nil {
+		return nil, fmt.Errorf("read sender: %w", err)
+	}
+	if len(from) != 1 {
+		return nil, fmt.Errorf("want 1 sender, found %d", len(from))
+	}
+	if len(allowed) > 0 && !slices.ContainsFunc(allowed, func(a string) bool {
+		return strings.EqualFold(a, from[0].Address)
+	}) {
+		return nil, fmt.Errorf("sender %s not allowed", from[0].Address)
+	}
+	return from[0], nil
+}
+
+// attribution returns the line which begins a comment posted from
+// a message sent by from, followed by a blank line.
+func attribution(from *mail.Address) string {
+	name := from.Address
+	if from.Name != "" {
+		name = from.Name + " <" + from.Address + ">"
+	}
+	return "Sent by mail from " + name + ":\n\n"
+}
+
+// textBody returns the decoded text/plain content of msg.
+// For multipart messages, the first text/plain part
+// in a supported character set is used.
+func textBody(msg *mail.Message) (string, error) {
+	ctype := msg.Header.Get("Content-Type")
+	if ctype == "" {
+		ctype = "text/plain"
+	}
+	return textPart(ctype, msg.Header.Get("Content-Transfer-Encoding"), msg.Body)
+}
+
+func textPart(ctype, encoding string, r io.Reader) (string, error) {
+	mediatype, params, err := mime.ParseMediaType(ctype)
+	if err != nil {
+		return "", fmt.Errorf("parse content type: %w", err)
+	}
+	if strings.HasPrefix(mediatype, "multipart/") {
+		mr := multipart.NewReader(r, params["boundary"])
+		for {
+			part, err := mr.NextPart()
+			if err == io.EOF {
+				return "", fmt.Errorf("no text/plain part in %s message", mediatype)
+			} else if err != nil {
+				return "", err
+			}
+			ct := part.Header.Get("Content-Type")
+			if ct == "" {
+				ct = "text/plain"
+			}
+			// multipart.Part decodes quoted-printable itself.
+			s, err := textPart(ct, part.Header.Get("Content-Transfer-Encoding"), part)
+			if err == nil {
+				return s, nil
+			}
+		}
+	}
+	if mediatype != "text/plain" {
+		return "", fmt.Errorf("unsupported content type %s", mediatype)
+	}
+	switch strings.ToLower(encoding) {
+	case "quoted-printable":
+		r = quotedprintable.NewReader(r)
+	case "base64":
+		r = base64.NewDecoder(base64.StdEncoding, r)
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	s, err := decodeCharset(b, params["charset"])
+	if err != nil {
+		return "", err
+	}
+	return strings.ReplaceAll(s, "\r\n", "\n"), nil
+}
+
+// decodeCharset returns the text of b, encoded in charset, as UTF-8.
+// Only UTF-8 and its subset US-ASCII, ISO 8859-1 and Windows-1252,
+// the character sets used by most mail clients, are supported.
+// Text without a charset, or in US-ASCII, is accepted if it is valid UTF-8.
+func decodeCharset(b []byte, charset string) (string, error) {
+	switch strings.ToLower(charset) {
+	case "", "us-ascii", "utf-8", "utf8":
+		if !utf8.Valid(b) {
+			return "", fmt.Errorf("invalid %s text", cmp.Or(charset, "us-ascii"))
+		}
+		return string(b), nil
+	case "iso-8859-1", "latin1":
+		return decodeLatin1(b, nil), nil
+	case "windows-1252", "cp1252":
+		return decodeLatin1(b, &windows1252), nil
+	}
+	return "", fmt.Errorf("unsupported charset %s", charset)
+}
+
+// decodeLatin1 decodes b as ISO 8859-1,
+// except that bytes from 0x80 to 0x9F are looked up in high, if not nil.
+func decodeLatin1(b []byte, high *[32]rune) string {
+	var sb strings.Builder
+	for _, c := range b {
+		r := rune(c)
+		if high != nil && c >= 0x80 && c <= 0x9f {
+			r = high[c-0x80]
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// windows1252 holds the characters encoded as 0x80 to 0x9F in Windows-1252.
+// Unassigned bytes are mapped to the control characters of the same value.
+var windows1252 = [32]rune{
+	'€', 0x81, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0x8d, 'Ž', 0x8f,
+	0x90, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0x9d, 'ž', 'Ÿ',
+}
+
+// stripQuoted removes quoted text from a reply:
+// lines beginning with ">", the attribution line introducing them
+// (such as "On Mon, Fred wrote:"), and any signature following a "-- " line.
+// The trailing space of the signature separator is often lost, so "--" is accepted too.
+func stripQuoted(body string) string {
+	lines := strings.Split(body, "\n")
+	var kept []string
+	for i, line := range lines {
+		if strings.TrimRight(line, " ") == "--" {
+			break
+		}
+		if strings.HasPrefix(line, ">") {
+			continue
+		}
+		if strings.HasSuffix(strings.TrimSpace(line), "wrote:") && quoteFollows(lines[i+1:]) {
+			continue
+		}
+		kept = append(kept, line)
+	}
+	// collapse runs of blank lines left where quotes were removed.
+	s := strings.Join(kept, "\n")
+	for strings.Contains(s, "\n\n\n") {
+		s = strings.ReplaceAll(s, "\n\n\n", "\n\n")
+	}
+	return strings.TrimSpace(s)
+}
+
+// quoteFollows reports whether the next non-blank line is quoted.
+func quoteFollows(lines []string) bool {
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		return strings.HasPrefix(line, ">")
+	}
+	return false
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"net/mail"
+	"strings"
+	"testing"
+)
+
+func TestIssueKey(t *testing.T) {
+	var tests = []struct {
+		header mail.Header
+		want   string
+	}{
+		{mail.Header{"In-Reply-To": {"<TEST-1@jira.example.com>"}}, "TEST-1"},
+		{mail.Header{"In-Reply-To": {"<TEST-1.10001@[::1]>"}}, "TEST-1"},
+		{
+			mail.Header{
+				"In-Reply-To": {"<abc123@mail.example.com>"},
+				"References":  {"<SRE-42@jira.example.com> <abc123@mail.example.com>"},
+			},
+			"SRE-42",
+		},
+		{mail.Header{"Subject": {"Re: [TEST-69] Printer on fire"}}, "TEST-69"},
+	}
+	for _, tt := range tests {
+		got, err := issueKey(tt.header)
+		if err != nil {
+			t.Errorf("issue key from %v: %v", tt.header, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("issue key from %v = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+	if _, err := issueKey(mail.Header{"Subject": {"hello"}}); err == nil {
+		t.Error("want error for message not referencing an issue")
+	}
+}
+
+func TestStripQuoted(t *testing.T) {
+	body := `I've restarted it.
+
+On Mon, 1 Jan 2024, Ann <ann@example.com> wrote:
+> The printer is on fire.
+>
+> Please help.
+
+Should be fine now.
+
+--
+Fred
+`
+	want := "I've restarted it.\n\nShould be fine now."
+	if got := stripQuoted(body); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestTextBody(t *testing.T) {
+	raw := "In-Reply-To: <TEST-1@jira.example.com>\r\n" +
+		"Content-Type: multipart/alternative; boundary=b\r\n" +
+		"\r\n" +
+		"--b\r\n" +
+		"Content-Type: text/plain; charset=utf-8\r\n" +
+		"Content-Transfer-Encoding: quoted-printable\r\n" +
+		"\r\n" +
+		"caf=C3=A9 is =\r\nopen\r\n" +
+		"--b\r\n" +
+		"Content-Type: text/html\r\n" +
+		"\r\n" +
+		"<p>caf&eacute; is open</p>\r\n" +
+		"--b--\r\n"
+	msg, err := mail.ReadMessage(strings.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := textBody(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "café is open"; strings.TrimSpace(got) != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestSender(t *testing.T) {
+	header := mail.Header{"From": {"Ann Jones <Ann@example.com>"}}
+	from, err := sender(header, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Sent by mail from Ann Jones <Ann@example.com>:\n\n"; attribution(from) != want {
+		t.Errorf("attribution = %q, want %q", attribution(from), want)
+	}
+	if _, err := sender(header, []string{"fred@example.com", "ann@example.com"}); err != nil {
+		t.Errorf("sender in allowed list: %v", err)
+	}
+	if _, err := sender(header, []string{"fred@example.com"}); err == nil {
+		t.Error("want error for sender not allowed")
+	}
+	for _, h := range []mail.Header{
+		{},
+		{"From": {"not an address"}},
+		{"From": {"ann@example.com, fred@example.com"}},
+	} {
+		if _, err := sender(h, nil); err == nil {
+			t.Errorf("sender from %v: want error", h)
+		}
+	}
+}
+
+func TestTextBodyCharset(t *testing.T) {
+	var tests = []struct {
+		ctype string
+		body  string
+		want  string
+	}{
+		{"text/plain; charset=iso-8859-1", "caf\xe9 is open", "café is open"},
+		{"text/plain; charset=Windows-1252", "\x93caf\xe9\x94 \x96 open", "“café” – open"},
+		{"text/plain; charset=utf-8", "café is open", "café is open"},
+		{"text/plain", "cafe is open", "cafe is open"},
+	}
+	for _, tt := range tests {
+		raw := "Content-Type: " + tt.ctype + "\r\n\r\n" + tt.body
+		msg, err := mail.ReadMessage(strings.NewReader(raw))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := textBody(msg)
+		if err != nil {
+			t.Errorf("%s: %v", tt.ctype, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.ctype, got, tt.want)
+		}
+	}
+
+	// Parts in unsupported character sets are skipped.
+	raw := "Content-Type: multipart/alternative; boundary=b\r\n" +
+		"\r\n" +
+		"--b\r\n" +
+		"Content-Type: text/plain; charset=koi8-r\r\n" +
+		"\r\n" +
+		"\xf0\xd2\xc9\xd7\xc5\xd4\r\n" +
+		"--b\r\n" +
+		"Content-Type: text/plain; charset=iso-8859-1\r\n" +
+		"\r\n" +
+		"caf\xe9\r\n" +
+		"--b--\r\n"
+	msg, err := mail.ReadMessage(strings.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := textBody(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "café"; strings.TrimSpace(got) != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	for _, ctype := range []string{"text/plain; charset=koi8-r", "text/plain; charset=utf-8"} {
+		msg, err := mail.ReadMessage(strings.NewReader("Content-Type: " + ctype + "\r\n\r\ncaf\xe9\r\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := textBody(msg); err == nil {
+			t.Errorf("%s: no error for undecodable text", ctype)
+		}
+	}
+}
@@ -1,15 +1,21 @@
 // Command jiraq lists Jira issues matching the provided Jira query.
 // Queries must be provided as a single quoted argument in JQL format,
 // such as "project = EXAMPLE and status = Done".
+// Queries which cannot be parsed locally are reported as a warning,
+// marking the position of the error, but are still sent to the server,
+// which understands more of JQL than the local parser.
 //
 // Its usage is:
 //
-//	jiraq [ -u url ] query
+//	jiraq [ -profile name ] [ -u url ] query
 //
 // The flags are:
 //
+//	-profile name
+//		Connect using the named profile from the configuration file.
+//		See package olowe.co/issues/jira/config for its format.
 //	-u url
-//		The URL pointing to the root of the JIRA REST API.
+//		The URL of the Jira server, overriding that of the profile.
 //
 //
 // # Examples
@@ -18,7 +24,7 @@
 //
 //	jiraq -u https://company.example.net 'project = SRE and status != done'
 //
-// Subsequent examples omit the "-u" flag for brevity.
+// Subsequent examples use the server from the default profile.
 // List all open tickets assigned to yourself in the project "SRE":
 //
 //	jiraq 'project = SRE and status != done and assignee = currentuser()'
@@ -30,38 +36,22 @@
 package main
 
 import (
-	"bytes"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
-	"net/url"
 	"os"
-	"path"
 	"strings"
+	"unicode/utf8"
 
 	"olowe.co/issues/jira"
+	"olowe.co/issues/jira/config"
 )
 
-func readJiraAuth() (user, pass string, err error) {
-	confDir, err := os.UserConfigDir()
-	if err != nil {
-		return "", "", err
-	}
-	b, err := os.ReadFile(path.Join(confDir, "atlassian/jira"))
-	if err != nil {
-		return "", "", err
-	}
-	b = bytes.TrimSpace(b)
-	u, p, ok := strings.Cut(string(b), ":")
-	if !ok {
-		return "", "", fmt.Errorf(`missing ":" between username and password`)
-	}
-	return u, p, nil
-}
-
-var apiRoot = flag.String("u", "http://[::1]:8080", "base URL for the JIRA API")
+var apiRoot = flag.String("u", "", "base URL for the JIRA API, overriding the profile")
+var profile = flag.String("profile", "", "connect using the configuration profile `name`")
 
-const usage = "usage: jiraq [-u url] query"
+const usage = "usage: jiraq [-profile name] [-u url] query"
 
 func init() {
 	log.SetPrefix("jiraq: ")
@@ -74,26 +64,35 @@ func main() {
 		log.Fatal(usage)
 	}
 
-	user, pass, err := readJiraAuth()
+	prof, err := config.Load("", *profile)
 	if err != nil {
-		log.Fatalf("read jira auth credentials: %v", err)
+		log.Fatalf("read configuration: %v", err)
 	}
-	u, err := url.Parse(*apiRoot)
-	if err != nil {
-		log.Fatalln("parse api url:", err)
-	}
-	u.Path = path.Join(u.Path, "rest/api/2")
-	client := &jira.Client{
-		APIRoot:  u,
-		Username: user,
-		Password: pass,
+	if *apiRoot != "" {
+		if err := prof.SetURL(*apiRoot); err != nil {
+			log.Fatalln("parse api url:", err)
+		}
 	}
+	client := prof.Client()
 
-	issues, err := client.SearchIssues(strings.Join(flag.Args(), " "))
-	if err != nil {
-		log.Fatal(err)
+	query := strings.Join(flag.Args(), " ")
+	if _, err := jira.ParseQuery(query); err != nil {
+		var serr *jira.SyntaxError
+		if errors.As(err, &serr) {
+			// Point to the error beneath the query.
+			indent := strings.Repeat(" ", utf8.RuneCountInString(query[:serr.Pos]))
+			log.Printf("warning: %v\n\t%s\n\t%s^", err, query, indent)
+		} else {
+			log.Println("warning:", err)
+		}
+	}
+	iter := client.Search(query)
+	for iter.Next() {
+		for _, is := range iter.Page() {
+			fmt.Printf("%s-%s\t%s\n", is.Project.Name(), is.Name(), is.Summary)
+		}
 	}
-	for _, is := range issues {
-		fmt.Printf("%s-%s\t%s\n", is.Project.Name(), is.Name(), is.Summary)
+	if err := iter.Err(); err != nil {
+		log.Fatal(err)
 	}
 }
@@ -0,0 +1,95 @@
+// Command jirasync mirrors Jira projects and issues into a local directory,
+// for browsing offline with Jira and jiraexport.
+//
+// Its usage is:
+//
+//	jirasync [ -profile name ] [ -q query ] [ -u url ] dir [ project ... ]
+//
+// Each named project is mirrored in full into dir.
+// Issues deleted from a mirrored project are removed from the mirror.
+// Running jirasync again fetches only the issues updated since the last run.
+//
+// The flags are:
+//
+//	-profile name
+//		Connect using the named profile from the configuration file.
+//		See package olowe.co/issues/jira/config for its format.
+//	-q query
+//		Also mirror the issues matching the JQL query, and their projects.
+//	-u url
+//		The URL of the Jira server, overriding that of the profile.
+//
+// # Examples
+//
+// Mirror the projects SRE and WEB into the directory jira:
+//
+//	jirasync jira SRE WEB
+//
+// Browse the mirror in Acme, and export it, without a network connection:
+//
+//	Jira -m jira
+//	jiraexport -m jira SRE-1234
+//
+// The layout of the mirror is described by MirrorClient
+// in package olowe.co/issues/jira.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"olowe.co/issues/jira"
+	"olowe.co/issues/jira/config"
+)
+
+var apiRoot = flag.String("u", "", "base URL for the JIRA API, overriding the profile")
+var profile = flag.String("profile", "", "connect using the configuration profile `name`")
+var query = flag.String("q", "", "mirror issues matching this JQL query")
+
+const usage = "usage: jirasync [-profile name] [-q query] [-u url] dir [project ...]"
+
+func init() {
+	log.SetPrefix("jirasync: ")
+	log.SetFlags(0)
+}
+
+func main() {
+	flag.Parse()
+	if flag.NArg() == 0 || (flag.NArg() == 1 && *query == "") {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+	dir := flag.Arg(0)
+
+	prof, err := config.Load("", *profile)
+	if err != nil {
+		log.Fatalf("read configuration: %v", err)
+	}
+	if *apiRoot != "" {
+		if err := prof.SetURL(*apiRoot); err != nil {
+			log.Fatalln("parse api url:", err)
+		}
+	}
+	client := prof.Client()
+
+	ctx := context.Background()
+	var failed bool
+	for _, project := range flag.Args()[1:] {
+		if err := jira.SyncProject(ctx, client, dir, project); err != nil {
+			log.Printf("sync %s: %v", project, err)
+			failed = true
+		}
+	}
+	if *query != "" {
+		if err := jira.SyncQuery(ctx, client, dir, *query); err != nil {
+			log.Printf("sync query: %v", err)
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
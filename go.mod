@@ -1,6 +1,6 @@
 module olowe.co/issues
 
-go 1.21
+go 1.22
 
 require (
 	9fans.net/go v0.0.7
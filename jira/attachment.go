@@ -0,0 +1,157 @@
+package jira
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// Attachment is a file attached to an issue.
+type Attachment struct {
+	ID       string    `json:"id"`
+	Filename string    `json:"filename"`
+	Author   User      `json:"author"`
+	Created  time.Time `json:"created"`
+	Size     int64     `json:"size"`
+	MimeType string    `json:"mimeType"`
+	// Content is the URL of the attachment's content.
+	Content string `json:"content"`
+}
+
+func (a *Attachment) UnmarshalJSON(b []byte) error {
+	type alias Attachment
+	aux := &struct {
+		Created string `json:"created"`
+		*alias
+	}{
+		alias: (*alias)(a),
+	}
+	if err := json.Unmarshal(b, aux); err != nil {
+		return err
+	}
+	var err error
+	a.Created, err = time.Parse(timestamp, aux.Created)
+	if err != nil {
+		return fmt.Errorf("parse created time: %w", err)
+	}
+	return nil
+}
+
+// OpenAttachment returns the content of the attachment.
+// The request is authenticated only if the content is served
+// from the same scheme and host as the client's APIRoot.
+// The caller must close the returned reader when finished with it.
+func (c *Client) OpenAttachment(a *Attachment) (io.ReadCloser, error) {
+	return c.OpenAttachmentContext(context.Background(), a)
+}
+
+// OpenAttachmentContext is like OpenAttachment, with requests made using ctx.
+func (c *Client) OpenAttachmentContext(ctx context.Context, a *Attachment) (io.ReadCloser, error) {
+	// Content is usually absolute, but resolve it against
+	// the API root in case it is not.
+	u, err := c.APIRoot.Parse(a.Content)
+	if err != nil {
+		return nil, fmt.Errorf("parse content url: %w", err)
+	}
+	resp, err := c.get(ctx, u.String())
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, responseError(resp)
+	}
+	return resp.Body, nil
+}
+
+// Attach uploads the contents of r as a file with the given name
+// and attaches it to the issue with the given key.
+// The entire contents are read into memory before uploading.
+func (c *Client) Attach(key, name string, r io.Reader) (*Attachment, error) {
+	return c.AttachContext(context.Background(), key, name, r)
+}
+
+// AttachContext is like Attach, with requests made using ctx.
+func (c *Client) AttachContext(ctx context.Context, key, name string, r io.Reader) (*Attachment, error) {
+	buf := &bytes.Buffer{}
+	mw := multipart.NewWriter(buf)
+	part, err := mw.CreateFormFile("file", name)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(part, r); err != nil {
+		return nil, fmt.Errorf("read %s: %w", name, err)
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	u := *c.APIRoot
+	u.Path = path.Join(u.Path, "issue", key, "attachments")
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	// Jira rejects uploads without this header as possible
+	// cross-site request forgery.
+	req.Header.Set("X-Atlassian-Token", "no-check")
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil, responseError(resp)
+	}
+	var attached []Attachment
+	if err := json.NewDecoder(resp.Body).Decode(&attached); err != nil {
+		return nil, fmt.Errorf("decode attachments: %w", err)
+	}
+	if len(attached) == 0 {
+		return nil, fmt.Errorf("no attachment in response")
+	}
+	return &attached[0], nil
+}
+
+// attachmentNames returns the names of the files holding attachments
+// in an issue's attachments directory: their filenames,
+// with slashes replaced, prefixed by their IDs where
+// a name is shared by other attachments or is not a valid file name.
+func attachmentNames(attachments []Attachment) []string {
+	names := make([]string, len(attachments))
+	for i, a := range attachments {
+		name := strings.ReplaceAll(a.Filename, "/", "_")
+		if name == "" || name == "." || name == ".." {
+			name = a.ID + "-" + name
+		}
+		names[i] = name
+	}
+	// Prefixing a name may make it the same as another,
+	// such as "1-log.txt" for a second "log.txt",
+	// so repeat until every name is unique.
+	// Each round separates the names of attachments with different IDs.
+	for range len(attachments) {
+		count := make(map[string]int)
+		for _, name := range names {
+			count[name]++
+		}
+		unique := true
+		for i, a := range attachments {
+			if count[names[i]] > 1 {
+				names[i] = a.ID + "-" + names[i]
+				unique = false
+			}
+		}
+		if unique {
+			break
+		}
+	}
+	return names
+}
@@ -0,0 +1,77 @@
+package jira_test
+
+import (
+	"io"
+	"io/fs"
+	"slices"
+	"strings"
+	"testing"
+
+	"olowe.co/issues/jira"
+)
+
+func TestAttachments(t *testing.T) {
+	srv, client := newServer(t)
+	const log = "date,jobs\n2011-03-14,3\n"
+	srv.AddAttachment("TEST-1", fred, "log/110314.csv", []byte(log))
+	srv.AddAttachment("TEST-1", fred, "times.png", []byte("\x89PNG\r\n\x1a\n"))
+	fsys := &jira.FS{Client: client}
+
+	dents, err := fs.ReadDir(fsys, "TEST/1/attachments")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, d := range dents {
+		names = append(names, d.Name())
+	}
+	want := []string{"log_110314.csv", "times.png"}
+	if !slices.Equal(names, want) {
+		t.Errorf("got attachments %v, want %v", names, want)
+	}
+	issue, err := client.Issue("TEST-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := fs.Stat(fsys, "TEST/1/attachments/times.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mtime := issue.Attachments[1].Created
+	if info.Size() != 8 || !info.ModTime().Equal(mtime) {
+		t.Errorf("stat times.png: got size %d, modified %s; want 8, %s", info.Size(), info.ModTime(), mtime)
+	}
+	got, err := fs.ReadFile(fsys, "TEST/1/attachments/log_110314.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != log {
+		t.Errorf("read attachment: got %q, want %q", got, log)
+	}
+	if _, err := fs.Stat(fsys, "TEST/1/attachments/nothing.txt"); err == nil {
+		t.Error("no error opening missing attachment")
+	}
+
+	const notes = "printer still on fire\n"
+	a, err := client.Attach("TEST-1", "notes.txt", strings.NewReader(notes))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Filename != "notes.txt" || a.Size != int64(len(notes)) {
+		t.Errorf("attached %s of size %d, want notes.txt of size %d", a.Filename, a.Size, len(notes))
+	}
+	rc, err := client.OpenAttachment(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	if b, err := io.ReadAll(rc); err != nil || string(b) != notes {
+		t.Errorf("read uploaded attachment: got %q, %v", b, err)
+	}
+	if b, err := fs.ReadFile(fsys, "TEST/1/attachments/notes.txt"); err != nil || string(b) != notes {
+		t.Errorf("read uploaded attachment from fs: got %q, %v", b, err)
+	}
+	if _, err := client.Attach("TEST-999", "notes.txt", strings.NewReader(notes)); err == nil {
+		t.Error("no error attaching to missing issue")
+	}
+}
@@ -0,0 +1,56 @@
+package jira
+
+import (
+	"fmt"
+	"net/http"
+
+	"golang.org/x/oauth2"
+)
+
+// An Authenticator adds credentials to each request sent by a Client.
+type Authenticator interface {
+	Authenticate(req *http.Request) error
+}
+
+// BasicAuth authenticates using HTTP basic authentication.
+// Jira Cloud expects the account's email address as Username
+// and an API token as Password; account passwords are not accepted.
+type BasicAuth struct {
+	Username string
+	Password string
+}
+
+func (a *BasicAuth) Authenticate(req *http.Request) error {
+	req.SetBasicAuth(a.Username, a.Password)
+	return nil
+}
+
+// BearerToken authenticates with a personal access token,
+// as supported by Jira Server and Data Center.
+type BearerToken string
+
+func (t BearerToken) Authenticate(req *http.Request) error {
+	req.Header.Set("Authorization", "Bearer "+string(t))
+	return nil
+}
+
+// OAuth2 returns an Authenticator using tokens from ts.
+// Tokens are refreshed by ts as they expire;
+// wrap ts with [oauth2.ReuseTokenSource] to avoid requesting
+// a new token for every request.
+func OAuth2(ts oauth2.TokenSource) Authenticator {
+	return &tokenSourceAuth{ts}
+}
+
+type tokenSourceAuth struct {
+	oauth2.TokenSource
+}
+
+func (a *tokenSourceAuth) Authenticate(req *http.Request) error {
+	tok, err := a.Token()
+	if err != nil {
+		return fmt.Errorf("get oauth2 token: %w", err)
+	}
+	tok.SetAuthHeader(req)
+	return nil
+}
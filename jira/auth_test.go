@@ -0,0 +1,106 @@
+package jira
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"golang.org/x/oauth2"
+)
+
+func TestAuth(t *testing.T) {
+	var tests = []struct {
+		auth Authenticator
+		want string
+	}{
+		{&BasicAuth{"fred", "secret"}, "Basic ZnJlZDpzZWNyZXQ="},
+		{BearerToken("abc123"), "Bearer abc123"},
+		{OAuth2(oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "xyz789"})), "Bearer xyz789"},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		var got string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			got = req.Header.Get("Authorization")
+			w.Write([]byte(`{"name": "fred"}`))
+		}))
+		u, err := url.Parse(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		client := &Client{APIRoot: u, Auth: tt.auth}
+		if _, err := client.Myself(); err != nil {
+			t.Errorf("%T: %v", tt.auth, err)
+		}
+		if got != tt.want {
+			t.Errorf("%T: got Authorization %q, want %q", tt.auth, got, tt.want)
+		}
+		srv.Close()
+	}
+}
+
+func TestDeprecatedBasicAuth(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		got = req.Header.Get("Authorization")
+		w.Write([]byte(`{"name": "fred"}`))
+	}))
+	defer srv.Close()
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &Client{APIRoot: u, Username: "fred", Password: "secret"}
+	if _, err := client.Myself(); err != nil {
+		t.Fatal(err)
+	}
+	if want := "Basic ZnJlZDpzZWNyZXQ="; got != want {
+		t.Errorf("got Authorization %q, want %q", got, want)
+	}
+	client.Auth = BearerToken("abc123")
+	if _, err := client.Myself(); err != nil {
+		t.Fatal(err)
+	}
+	if want := "Bearer abc123"; got != want {
+		t.Errorf("with Auth set, got Authorization %q, want %q", got, want)
+	}
+}
+
+func TestAuthOtherHost(t *testing.T) {
+	var got string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		got = req.Header.Get("Authorization")
+		w.Write([]byte("hello"))
+	})
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+	other := httptest.NewServer(handler)
+	defer other.Close()
+	u, err := url.Parse(srv.URL + "/rest/api/2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &Client{APIRoot: u, Auth: &BasicAuth{"fred", "secret"}}
+
+	var tests = []struct {
+		content string
+		want    string
+	}{
+		{srv.URL + "/secure/attachment/10001/hello.txt", "Basic ZnJlZDpzZWNyZXQ="},
+		{"/secure/attachment/10001/hello.txt", "Basic ZnJlZDpzZWNyZXQ="},
+		{other.URL + "/secure/attachment/10001/hello.txt", ""},
+	}
+	for _, tt := range tests {
+		got = ""
+		rc, err := client.OpenAttachment(&Attachment{Content: tt.content})
+		if err != nil {
+			t.Errorf("open %s: %v", tt.content, err)
+			continue
+		}
+		rc.Close()
+		if got != tt.want {
+			t.Errorf("open %s: got Authorization %q, want %q", tt.content, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package jira
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cache holds issues and project listings fetched by an FS,
+// so that browsing the same issues again makes few or no requests.
+// A Cache may be shared by many FS values and is safe for concurrent use.
+//
+// Issues are keyed by their issue key.
+// An issue fetched within TTL is served without any request.
+// Once TTL has passed, the issue is revalidated by requesting only
+// its updated time; the full issue is fetched again only if it has changed.
+// Project listings are served for TTL then fetched again.
+type Cache struct {
+	// TTL is how long entries are served without contacting the server.
+	TTL time.Duration
+	// Dir, if not empty, names a directory where issues are also stored,
+	// so they survive between programs.
+	// Issues read from Dir are always revalidated before use.
+	Dir string
+
+	mu     sync.Mutex
+	issues map[string]*cachedIssue
+	lists  map[string]*cachedList
+}
+
+type cachedIssue struct {
+	issue   *Issue
+	raw     []byte
+	checked time.Time // when last fetched or revalidated
+}
+
+type cachedList struct {
+	issues  []Issue
+	fetched time.Time
+}
+
+// NewCache returns an empty in-memory cache with the given TTL.
+func NewCache(ttl time.Duration) *Cache {
+	return &Cache{TTL: ttl}
+}
+
+// Invalidate removes key from the cache.
+// Key is either an issue key, such as TEST-1,
+// or a project key, such as TEST, to remove the project's issue listing.
+// Calling Invalidate on a nil Cache does nothing.
+func (c *Cache) Invalidate(key string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !strings.Contains(key, "-") {
+		delete(c.lists, key)
+		return
+	}
+	delete(c.issues, key)
+	if c.Dir != "" {
+		os.Remove(c.diskName(key))
+	}
+}
+
+// issue returns the issue with the given key, fetching it with client
+// if it is missing or has been updated since it was cached.
+// The returned Issue is shared and must not be modified.
+func (c *Cache) issue(ctx context.Context, client *Client, key string) (*Issue, error) {
+	c.mu.Lock()
+	e := c.issues[key]
+	c.mu.Unlock()
+	if e == nil && c.Dir != "" {
+		e = c.load(key)
+	}
+	if e != nil {
+		if time.Since(e.checked) < c.TTL {
+			return e.issue, nil
+		}
+		updated, err := client.issueUpdated(ctx, key)
+		if errors.Is(err, ErrNotFound) {
+			c.Invalidate(key)
+			return nil, err
+		} else if err != nil {
+			return nil, err
+		}
+		if updated.Equal(e.issue.Updated) {
+			c.store(key, &cachedIssue{e.issue, e.raw, time.Now()})
+			return e.issue, nil
+		}
+	}
+
+	raw, err := client.issueJSON(ctx, key, "")
+	if errors.Is(err, ErrNotFound) {
+		c.Invalidate(key)
+		return nil, err
+	} else if err != nil {
+		return nil, err
+	}
+	var is Issue
+	if err := json.Unmarshal(raw, &is); err != nil {
+		return nil, fmt.Errorf("decode issue: %w", err)
+	}
+	c.store(key, &cachedIssue{&is, raw, time.Now()})
+	if c.Dir != "" {
+		if err := c.save(key, raw); err != nil {
+			return nil, fmt.Errorf("save %s in cache: %w", key, err)
+		}
+	}
+	return &is, nil
+}
+
+func (c *Cache) store(key string, e *cachedIssue) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.issues == nil {
+		c.issues = make(map[string]*cachedIssue)
+	}
+	c.issues[key] = e
+}
+
+// comment returns the comment id on the issue with the given key.
+// Comments are found in the cached issue where possible.
+// Jira may not include every comment of an issue with many comments,
+// so comments missing from the issue are requested individually.
+func (c *Cache) comment(ctx context.Context, client *Client, key, id string) (*Comment, error) {
+	is, err := c.issue(ctx, client, key)
+	if err != nil {
+		return nil, err
+	}
+	for i := range is.Comments {
+		if is.Comments[i].ID == id {
+			return &is.Comments[i], nil
+		}
+	}
+	return client.CommentContext(ctx, key, id)
+}
+
+// projectIssues returns the issues in the named project.
+func (c *Cache) projectIssues(ctx context.Context, client *Client, project string) ([]Issue, error) {
+	c.mu.Lock()
+	l := c.lists[project]
+	c.mu.Unlock()
+	if l != nil && time.Since(l.fetched) < c.TTL {
+		return l.issues, nil
+	}
+	issues, err := client.IssuesContext(ctx, project)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.lists == nil {
+		c.lists = make(map[string]*cachedList)
+	}
+	c.lists[project] = &cachedList{issues, time.Now()}
+	return issues, nil
+}
+
+func (c *Cache) diskName(key string) string {
+	return filepath.Join(c.Dir, key+".json")
+}
+
+// load reads the issue with the given key from Dir.
+// The entry is returned as never checked so that it is revalidated.
+func (c *Cache) load(key string) *cachedIssue {
+	raw, err := os.ReadFile(c.diskName(key))
+	if err != nil {
+		return nil
+	}
+	var is Issue
+	if err := json.Unmarshal(raw, &is); err != nil {
+		return nil
+	}
+	return &cachedIssue{issue: &is, raw: raw}
+}
+
+// save writes the issue with the given key to Dir,
+// replacing any previous version in full.
+func (c *Cache) save(key string, raw []byte) error {
+	return writeFile(c.diskName(key), raw)
+}
+
+// writeFile writes b to the named file, creating its directory if needed.
+// The file is replaced in one step, so readers never see a partial write.
+func writeFile(name string, b []byte) error {
+	dir := filepath.Dir(name)
+	if err := os.MkdirAll(dir, 0o700); err != nil && !errors.Is(err, fs.ErrExist) {
+		return err
+	}
+	f, err := os.CreateTemp(dir, filepath.Base(name)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), name)
+}
@@ -0,0 +1,165 @@
+package jira_test
+
+import (
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"olowe.co/issues/jira"
+)
+
+// recorder records the requests sent through the wrapped transport.
+type recorder struct {
+	http.RoundTripper
+	mu       sync.Mutex
+	requests []*http.Request
+}
+
+// record makes client send its requests through a new recorder.
+func record(client *jira.Client) *recorder {
+	rec := &recorder{RoundTripper: client.Client.Transport}
+	client.Client = &http.Client{Transport: rec}
+	return rec
+}
+
+func (rec *recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	rec.mu.Lock()
+	rec.requests = append(rec.requests, req)
+	rec.mu.Unlock()
+	return rec.RoundTripper.RoundTrip(req)
+}
+
+// take returns the requests recorded since the last call to take.
+func (rec *recorder) take() []*http.Request {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	reqs := rec.requests
+	rec.requests = nil
+	return reqs
+}
+
+// count returns the number of requests recorded since the last call
+// to count or take.
+func (rec *recorder) count() int { return len(rec.take()) }
+
+// fetches returns the number of reqs fetching the issue key
+// with the given fields, or in full if fields is empty.
+func fetches(reqs []*http.Request, key, fields string) int {
+	var n int
+	for _, req := range reqs {
+		if strings.HasSuffix(req.URL.Path, "/issue/"+key) && req.URL.Query().Get("fields") == fields {
+			n++
+		}
+	}
+	return n
+}
+
+// browse walks an issue as a user might in Acme:
+// opening the issue directory, the issue, and its comments.
+// The history, whose changelog is never cached, is left out.
+func browse(t *testing.T, fsys fs.FS) {
+	t.Helper()
+	if _, err := fs.ReadDir(fsys, "TEST"); err != nil {
+		t.Fatal(err)
+	}
+	dents, err := fs.ReadDir(fsys, "TEST/1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range dents {
+		if d.IsDir() || d.Name() == "history" {
+			continue
+		}
+		name := path.Join("TEST/1", d.Name())
+		if _, err := fs.Stat(fsys, name); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fs.ReadFile(fsys, name); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCache(t *testing.T) {
+	srv, client := newServer(t)
+	rec := record(client)
+
+	browse(t, &jira.FS{Client: client})
+	uncached := rec.count()
+
+	cache := jira.NewCache(time.Hour)
+	browse(t, &jira.FS{Client: client, Cache: cache})
+	first := rec.take()
+	if len(first)*3 > uncached {
+		t.Errorf("browsing with empty cache made %d requests, want far fewer than %d without", len(first), uncached)
+	}
+	// A new FS sharing the cache needs only the project list.
+	browse(t, &jira.FS{Client: client, Cache: cache})
+	if n := rec.count(); n > 1 {
+		t.Errorf("browsing cached issue made %d requests, want at most 1", n)
+	}
+
+	// Expired entries are revalidated cheaply
+	// and served from the cache if unchanged.
+	cache.TTL = 0
+	fsys := &jira.FS{Client: client, Cache: cache}
+	if _, err := fs.ReadFile(fsys, "TEST/1/issue"); err != nil {
+		t.Fatal(err)
+	}
+	reqs := rec.take()
+	if fetches(reqs, "TEST-1", "updated") == 0 {
+		t.Error("expired issue not revalidated")
+	}
+	if n := fetches(reqs, "TEST-1", ""); n > 0 {
+		t.Errorf("revalidating unchanged issue fetched it in full %d times", n)
+	}
+
+	// Invalidated issues are fetched again.
+	cache.TTL = time.Hour
+	cache.Invalidate("TEST-1")
+	if _, err := fs.ReadFile(fsys, "TEST/1/issue"); err != nil {
+		t.Fatal(err)
+	}
+	if n := fetches(rec.take(), "TEST-1", ""); n != 1 {
+		t.Errorf("reading invalidated issue fetched it %d times, want 1", n)
+	}
+
+	// Deleted issues are not served from the cache after expiry.
+	cache.TTL = 0
+	srv.DeleteIssue("TEST-1")
+	if _, err := fs.Stat(fsys, "TEST/1/issue"); !os.IsNotExist(err) {
+		t.Errorf("want not exist error for deleted issue, got %v", err)
+	}
+}
+
+func TestCacheDir(t *testing.T) {
+	_, client := newServer(t)
+	rec := record(client)
+	dir := t.TempDir()
+	fsys := &jira.FS{Client: client, Cache: &jira.Cache{TTL: time.Hour, Dir: dir}}
+	if _, err := fs.ReadFile(fsys, "TEST/1/issue"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path.Join(dir, "TEST-1.json")); err != nil {
+		t.Errorf("issue not stored on disk: %v", err)
+	}
+	rec.take()
+
+	// A fresh cache reads the stored issue but revalidates it.
+	fsys = &jira.FS{Client: client, Cache: &jira.Cache{TTL: time.Hour, Dir: dir}}
+	if _, err := fs.ReadFile(fsys, "TEST/1/issue"); err != nil {
+		t.Fatal(err)
+	}
+	reqs := rec.take()
+	if n := fetches(reqs, "TEST-1", ""); n > 0 {
+		t.Errorf("fetched issue stored on disk in full %d times", n)
+	}
+	if fetches(reqs, "TEST-1", "updated") == 0 {
+		t.Error("issue loaded from disk was not revalidated")
+	}
+}
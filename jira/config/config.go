@@ -0,0 +1,335 @@
+// Package config reads the configuration shared by the Jira commands.
+//
+// The configuration file holds one or more profiles,
+// each describing how to connect to a Jira server.
+// Each line is a key and value separated by whitespace.
+// Blank lines and lines beginning with "#" are ignored.
+// Keys before the first profile line belong to the profile named "default".
+// For example:
+//
+//	url https://jira.example.com
+//	username fred@example.com
+//	apitoken ATATT3xFfGF0
+//
+//	profile work
+//	url https://jira.work.example.net
+//	pat NjI4MjU3OTk0MzY3
+//	field Story Points
+//	field Sprint
+//
+// The keys are:
+//
+//	profile name
+//		Start a new profile with the given name.
+//	url url
+//		The URL of the Jira server.
+//		The path to the REST API, /rest/api/2, is added if not present.
+//	username name
+//		The name to authenticate as, used with password or apitoken.
+//		Jira Cloud expects an email address.
+//	password password
+//		Authenticate using HTTP basic authentication.
+//	apitoken token
+//		Authenticate with an API token, as used by Jira Cloud.
+//	pat token
+//		Authenticate with a personal access token, as used by
+//		Jira Server and Data Center, sent as a bearer token.
+//	timeout duration
+//		Abandon requests taking longer than duration,
+//		in the format accepted by time.ParseDuration, such as 30s.
+//		By default requests have no timeout.
+//	field name
+//		Show the named custom field, such as "Story Points",
+//		as a header in issue files, where it may be edited.
+//		The name may also be a field ID, such as customfield_10016.
+//		The key may be repeated to show more fields.
+//	clientid id
+//	clientsecret secret
+//	tokenurl url
+//	refreshtoken token
+//		Authenticate with OAuth 2.0 access tokens obtained from tokenurl
+//		using the refresh token and client credentials.
+//		The client secret may be omitted for public clients.
+//
+// Only one of password, apitoken, pat, or the OAuth 2.0 keys may be used
+// in a profile. A profile without credentials sends unauthenticated requests.
+//
+// The default file is atlassian/jira in the directory returned by [os.UserConfigDir],
+// such as $HOME/.config/atlassian/jira.
+// The environment variable JIRA_CONFIG names a different file,
+// and JIRA_PROFILE selects a profile when none is given explicitly.
+package config
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+	"olowe.co/issues/jira"
+)
+
+// DefaultProfile is the name of the profile holding keys
+// which precede any profile line.
+const DefaultProfile = "default"
+
+const apiPath = "rest/api/2"
+
+// Profile describes how to connect to one Jira server.
+type Profile struct {
+	Name string
+	// APIRoot is the root of the Jira REST API,
+	// such as https://jira.example.com/rest/api/2.
+	APIRoot  *url.URL
+	Username string
+	// Password is a password or API token
+	// used with Username in HTTP basic authentication.
+	Password string
+	// Token is a personal access token sent as a bearer token.
+	Token string
+	// OAuth2 and RefreshToken, if set, are used to obtain
+	// OAuth 2.0 access tokens.
+	OAuth2       *oauth2.Config
+	RefreshToken string
+	// Timeout limits the time taken by each request.
+	Timeout time.Duration
+	// Fields names the custom fields shown in issue files.
+	// See jira.FS.
+	Fields []string
+}
+
+// Client returns a new client connecting to the profile's server.
+// Failed requests are retried according to jira.DefaultRetryPolicy.
+func (p *Profile) Client() *jira.Client {
+	retry := jira.DefaultRetryPolicy
+	return &jira.Client{
+		APIRoot:        p.APIRoot,
+		Auth:           p.Auth(),
+		RequestTimeout: p.Timeout,
+		Retry:          &retry,
+	}
+}
+
+// Auth returns the Authenticator for the profile's credentials,
+// or nil if the profile has none.
+func (p *Profile) Auth() jira.Authenticator {
+	switch {
+	case p.Token != "":
+		return jira.BearerToken(p.Token)
+	case p.OAuth2 != nil:
+		tok := &oauth2.Token{RefreshToken: p.RefreshToken}
+		return jira.OAuth2(p.OAuth2.TokenSource(context.Background(), tok))
+	case p.Username != "" || p.Password != "":
+		return &jira.BasicAuth{Username: p.Username, Password: p.Password}
+	}
+	return nil
+}
+
+// DefaultFile returns the name of the configuration file to read:
+// the value of JIRA_CONFIG if set, otherwise atlassian/jira
+// in the user's configuration directory.
+func DefaultFile() (string, error) {
+	if name := os.Getenv("JIRA_CONFIG"); name != "" {
+		return name, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "atlassian", "jira"), nil
+}
+
+// Load reads the named profile from the configuration file name.
+// If name is empty, DefaultFile is read.
+// If profile is empty, the profile named by JIRA_PROFILE is used,
+// or DefaultProfile if that is unset.
+func Load(name, profile string) (*Profile, error) {
+	if name == "" {
+		var err error
+		name, err = DefaultFile()
+		if err != nil {
+			return nil, fmt.Errorf("find configuration file: %w", err)
+		}
+	}
+	if profile == "" {
+		profile = os.Getenv("JIRA_PROFILE")
+	}
+	if profile == "" {
+		profile = DefaultProfile
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	profiles, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	for i := range profiles {
+		if profiles[i].Name == profile {
+			return &profiles[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%s: no profile named %q", name, profile)
+}
+
+// ErrLegacyFormat is returned when parsing a configuration
+// written as a single "username:password" line,
+// the format formerly read by jiraq and jiraexport.
+var ErrLegacyFormat = errors.New(`legacy "username:password" format: write "username" and "password" lines instead`)
+
+// Parse parses the profiles in a configuration file read from r.
+func Parse(r io.Reader) ([]Profile, error) {
+	var profiles []Profile
+	current := func() *Profile {
+		if len(profiles) == 0 {
+			profiles = append(profiles, Profile{Name: DefaultProfile})
+		}
+		return &profiles[len(profiles)-1]
+	}
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		k, v, ok := strings.Cut(line, " ")
+		v = strings.TrimSpace(v)
+		if len(profiles) == 0 && strings.Contains(k, ":") {
+			return nil, ErrLegacyFormat
+		}
+		if !ok {
+			return nil, fmt.Errorf("line %d: key %s: expected whitespace after configuration key", n, k)
+		} else if v == "" {
+			return nil, fmt.Errorf("line %d: key %s: missing parameter", n, k)
+		}
+		switch k {
+		case "profile":
+			for _, p := range profiles {
+				if p.Name == v {
+					return nil, fmt.Errorf("line %d: duplicate profile %q", n, v)
+				}
+			}
+			profiles = append(profiles, Profile{Name: v})
+		case "url":
+			u, err := parseURL(v)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", n, err)
+			}
+			current().APIRoot = u
+		case "username":
+			current().Username = v
+		case "password", "apitoken":
+			p := current()
+			if p.Password != "" {
+				return nil, fmt.Errorf("line %d: profile %s: only one of password or apitoken may be set", n, p.Name)
+			}
+			p.Password = v
+		case "pat":
+			current().Token = v
+		case "timeout":
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: parse timeout: %w", n, err)
+			}
+			current().Timeout = d
+		case "clientid":
+			current().oauth2().ClientID = v
+		case "clientsecret":
+			current().oauth2().ClientSecret = v
+		case "tokenurl":
+			if _, err := url.Parse(v); err != nil {
+				return nil, fmt.Errorf("line %d: parse token url: %w", n, err)
+			}
+			current().oauth2().Endpoint.TokenURL = v
+		case "refreshtoken":
+			current().RefreshToken = v
+		case "field":
+			p := current()
+			p.Fields = append(p.Fields, v)
+		default:
+			return nil, fmt.Errorf("line %d: unknown configuration key %q", n, k)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	for _, p := range profiles {
+		if err := p.check(); err != nil {
+			return nil, fmt.Errorf("profile %s: %w", p.Name, err)
+		}
+	}
+	return profiles, nil
+}
+
+func (p *Profile) oauth2() *oauth2.Config {
+	if p.OAuth2 == nil {
+		p.OAuth2 = &oauth2.Config{}
+	}
+	return p.OAuth2
+}
+
+func (p *Profile) check() error {
+	if p.APIRoot == nil {
+		return errors.New("missing url")
+	}
+	var n int
+	for _, set := range []bool{p.Password != "", p.Token != "", p.OAuth2 != nil || p.RefreshToken != ""} {
+		if set {
+			n++
+		}
+	}
+	if n > 1 {
+		return errors.New("only one of password, apitoken, pat or oauth2 credentials may be set")
+	}
+	if p.Password != "" && p.Username == "" {
+		return errors.New("missing username")
+	}
+	if p.OAuth2 != nil || p.RefreshToken != "" {
+		switch {
+		case p.OAuth2 == nil || p.OAuth2.ClientID == "":
+			return errors.New("missing clientid")
+		case p.OAuth2.Endpoint.TokenURL == "":
+			return errors.New("missing tokenurl")
+		case p.RefreshToken == "":
+			return errors.New("missing refreshtoken")
+		}
+	}
+	return nil
+}
+
+// parseURL parses the URL of a Jira server,
+// adding the path to the REST API if it is missing.
+func parseURL(s string) (*url.URL, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("parse url: %w", err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("parse url %q: missing scheme or host", s)
+	}
+	if !strings.Contains(u.Path, "/rest/api/") {
+		u.Path = path.Join("/", u.Path, apiPath)
+	}
+	return u, nil
+}
+
+// SetURL replaces the profile's server URL with rawURL,
+// as given on a command line.
+func (p *Profile) SetURL(rawURL string) error {
+	u, err := parseURL(rawURL)
+	if err != nil {
+		return err
+	}
+	p.APIRoot = u
+	return nil
+}
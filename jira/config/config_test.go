@@ -0,0 +1,155 @@
+package config
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testConfig = `
+# the default profile
+url https://jira.example.com
+username fred@example.com
+apitoken abc123
+
+profile work
+url https://jira.work.example.net/jira/rest/api/2
+pat xyz789
+timeout 30s
+field Story Points
+field customfield_10020
+`
+
+func TestParse(t *testing.T) {
+	profiles, err := Parse(strings.NewReader(testConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(profiles) != 2 {
+		t.Fatalf("got %d profiles, want 2", len(profiles))
+	}
+	def := profiles[0]
+	if def.Name != DefaultProfile {
+		t.Errorf("first profile named %q, want %q", def.Name, DefaultProfile)
+	}
+	if got, want := def.APIRoot.String(), "https://jira.example.com/rest/api/2"; got != want {
+		t.Errorf("default api root = %s, want %s", got, want)
+	}
+	if def.Username != "fred@example.com" || def.Password != "abc123" {
+		t.Errorf("default credentials = %q %q", def.Username, def.Password)
+	}
+	work := profiles[1]
+	if got, want := work.APIRoot.String(), "https://jira.work.example.net/jira/rest/api/2"; got != want {
+		t.Errorf("work api root = %s, want %s", got, want)
+	}
+	if work.Token != "xyz789" {
+		t.Errorf("work token = %q, want %q", work.Token, "xyz789")
+	}
+	if work.Timeout != 30*time.Second {
+		t.Errorf("work timeout = %s, want 30s", work.Timeout)
+	}
+	if len(work.Fields) != 2 || work.Fields[0] != "Story Points" || work.Fields[1] != "customfield_10020" {
+		t.Errorf("work fields = %q, want Story Points and customfield_10020", work.Fields)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	var tests = []struct {
+		name   string
+		config string
+	}{
+		{"legacy", "fred:secret\n"},
+		{"no url", "username fred\npassword secret\n"},
+		{"no username", "url https://jira.example.com\npassword secret\n"},
+		{"pat and password", "url https://jira.example.com\nusername fred\npassword secret\npat xyz\n"},
+		{"unknown key", "url https://jira.example.com\ncolour blue\n"},
+		{"duplicate profile", "profile a\nurl https://a.example.com\nprofile a\n"},
+		{"oauth2 without refresh token", "url https://jira.example.com\nclientid abc\ntokenurl https://auth.example.com/token\n"},
+		{"oauth2 and pat", "url https://jira.example.com\npat xyz\nclientid abc\ntokenurl https://auth.example.com/token\nrefreshtoken def\n"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(strings.NewReader(tt.config))
+		if err == nil {
+			t.Errorf("%s: nil error", tt.name)
+		}
+	}
+	_, err := Parse(strings.NewReader("fred:secret\n"))
+	if !errors.Is(err, ErrLegacyFormat) {
+		t.Errorf("want %v, got %v", ErrLegacyFormat, err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "jira")
+	if err := os.WriteFile(name, []byte(testConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("JIRA_CONFIG", name)
+	t.Setenv("JIRA_PROFILE", "work")
+	p, err := Load("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Name != "work" {
+		t.Errorf("loaded profile %q, want work from JIRA_PROFILE", p.Name)
+	}
+	p, err = Load("", DefaultProfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Name != DefaultProfile {
+		t.Errorf("loaded profile %q, want %q", p.Name, DefaultProfile)
+	}
+	if _, err := Load("", "nothing"); err == nil {
+		t.Error("nil error loading missing profile")
+	}
+}
+
+func TestAuth(t *testing.T) {
+	config := `
+url https://jira.example.com
+clientid abc
+tokenurl https://auth.example.com/oauth/token
+refreshtoken def
+
+profile anon
+url https://jira.example.net
+`
+	profiles, err := Parse(strings.NewReader(config))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := profiles[0]
+	if p.OAuth2 == nil || p.OAuth2.ClientID != "abc" || p.OAuth2.Endpoint.TokenURL != "https://auth.example.com/oauth/token" {
+		t.Errorf("unexpected oauth2 config %+v", p.OAuth2)
+	}
+	if p.Auth() == nil {
+		t.Error("nil authenticator for oauth2 profile")
+	}
+	if auth := profiles[1].Auth(); auth != nil {
+		t.Errorf("profile without credentials has authenticator %T", auth)
+	}
+}
+
+func TestBearer(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Authorization") != "Bearer xyz789" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"name": "fred"}`))
+	}))
+	defer srv.Close()
+	profiles, err := Parse(strings.NewReader("url " + srv.URL + "\npat xyz789\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := profiles[0].Client().Myself(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,9 +1,11 @@
 /*
 Jira is a program to interact with Jira issues from the Acme editor.
-Projects, issues and comments are presented as a virtual read-only filesystem
+Projects, issues and comments are presented as a virtual filesystem
 (using package [io/fs])
 which can be browsed in the usual way Acme handles filesystems served
 by the host system.
+Issues and comments are edited by writing to their files,
+as described below.
 
 The filesystem root holds project directories.
 Within each project are the project's issues, one directory entry per issue.
@@ -16,9 +18,79 @@ The filepaths for issues TEST-1, TEST-2, and WEB-27 would be:
 Each issue directory has a file named "issue"
 holding a textual representation of the issue and a listing of comments.
 For example, TEST/1/issue.
+Its headers hold the issue's metadata, such as status, resolution,
+type, priority, labels, components, versions, due date and parent issue.
+Linked issues are listed under a header for each relation,
+such as "Blocks: TEST-4, TEST-5" or "Is-Duplicated-By: TEST-2",
+and sub-tasks under the Subtasks header.
+Custom fields named by "field" lines in the configuration file
+are shown as further headers, such as "Story-Points: 3".
 
 Comments are available as numbered files alongside the issue file.
 Comment 69 of issue TEST-420 can be accessed at TEST/420/69.
+The file named "thread" holds the issue's description followed by
+the full text of every comment, oldest first.
+For example, TEST/420/thread.
+The file named "history" lists the changes made to the issue, oldest first,
+such as "* Fred Smith assigned Ann Jones (2024-03-01 12:00:00)".
+Files attached to the issue are in the directory named "attachments",
+such as TEST/420/attachments/screenshot.png.
+Attachments whose names would otherwise clash are prefixed by their IDs,
+as in 10001-screenshot.png.
+Their contents are downloaded from Jira as they are read.
+Files may be attached with the jiraattach command.
+
+Descriptions and comments are converted from Jira text formatting
+to plain text in the style of Go doc comments,
+the same format accepted when writing comments.
+Executing Raw in a window toggles between the plain text
+and the original Jira text formatting.
+The -r flag shows Jira text formatting by default.
+
+Executing Put in an issue window writes the window's contents back to Jira.
+Changes to the Subject and Assignee headers, to the headers of custom fields
+and to the description are applied to the issue.
+A changed Status header moves the issue to that status in its workflow.
+Users, both assignees and those in custom fields, are shown
+and may be given by mail address,
+as in "Assignee: Ann Jones <ann@example.com>",
+or by username;
+addresses are looked up in Jira to find the user.
+Adding an issue key to a link header, such as "Relates-To: TEST-7",
+links the issue to it; removing a key removes that link.
+Deleting a link header altogether leaves the issue's links unchanged.
+Other headers are ignored.
+
+Executing Transition with a name, such as "Transition Done",
+moves the issue through the named transition or to the named status.
+Without a name, the issue's available transitions are listed.
+
+Comments written by the authenticated user may be edited
+by executing Put in the comment's window, or removed by executing Delete.
+As with new comments, the text is converted to Jira text formatting
+unless the window is showing raw text.
+
+Executing New opens a window named PROJ/new holding a template issue
+for the project of the current window (or the project given as an argument,
+as in "New TEST").
+The Subject, Type, Assignee and Labels headers are filled in,
+and the description written below them.
+Executing Post creates the issue and renames the window to the new issue.
+
+Issues are kept in memory for a minute so that browsing is quick;
+after that they are checked for updates before being shown again.
+Executing Get in a window always reads its contents afresh.
+
+Deleting a window abandons any requests still in progress for it.
+
+The server and credentials are read from a configuration file,
+by default $HOME/.config/atlassian/jira, or the file named as an argument.
+The -profile flag selects one of several servers described in the file.
+See package olowe.co/issues/jira/config for details.
+
+The -m flag browses a mirror of projects written by jirasync
+instead of connecting to Jira, for reading issues offline.
+The mirror cannot be changed; writing to it fails.
 
 https:developer.atlassian.com/cloud/jira/platform/rest/v2/
 https:jira.atlassian.com/rest/api/2/issue/JRA-9
@@ -0,0 +1,161 @@
+package jira
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/mail"
+	"net/textproto"
+	"path"
+	"strings"
+)
+
+// WriteFile edits the issue at the named issue file, such as TEST/1/issue.
+// Data is read in the same format as the contents of the issue file.
+// The Subject and Assignee headers and the description in the message body
+// are compared against the current issue, and any changed fields are updated.
+// A changed Status header moves the issue through its workflow
+// as described by Client.Transition.
+// The Assignee header names a user by username or mail address,
+// which is looked up as described by Client.LookupUser.
+// Headers of the fields named in fsys.CustomFields are compared too;
+// an empty header clears its field, and a missing header leaves it unchanged.
+// Headers of links, such as "Blocks: TEST-4, TEST-5", are compared
+// against the issue's links, and links are added or removed to match;
+// a missing link header leaves the links of its relation unchanged.
+// Any link type known to Jira may be used, as in "Is-Cloned-By: TEST-2".
+// Unless fsys.Raw is set, the description is converted to
+// Jira text formatting with ToJTF.
+// Other headers and the listing of comments are ignored.
+func (fsys *FS) WriteFile(name string, data []byte) error {
+	if !fs.ValidPath(name) {
+		return &fs.PathError{Op: "write", Path: name, Err: fs.ErrInvalid}
+	}
+	elems := strings.Split(path.Clean(name), "/")
+	if len(elems) != 3 || elems[2] != "issue" {
+		return &fs.PathError{Op: "write", Path: name, Err: fs.ErrPermission}
+	}
+	key := elems[0] + "-" + elems[1]
+	if err := fsys.init(); err != nil {
+		return &fs.PathError{Op: "write", Path: name, Err: err}
+	}
+	// Compare against the issue as it is now, not as it was cached.
+	old, err := fsys.Client.IssueContext(fsys.context(), key)
+	if err != nil {
+		return &fs.PathError{Op: "write", Path: name, Err: err}
+	}
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		return &fs.PathError{Op: "write", Path: name, Err: fmt.Errorf("parse issue: %w", err)}
+	}
+	lookup := func(s string) (*User, error) {
+		return fsys.Client.LookupUserContext(fsys.context(), s)
+	}
+	fields, status, err := issueChanges(old, msg, fsys.Raw, fsys.root.fields, lookup)
+	if err != nil {
+		return &fs.PathError{Op: "write", Path: name, Err: err}
+	}
+	// Link types are only needed to find the relation
+	// of a header not already in the issue file.
+	var types []LinkType
+	if newHeaders(old, msg.Header, fsys.root.fields) {
+		types, err = fsys.Client.LinkTypesContext(fsys.context())
+		if err != nil {
+			return &fs.PathError{Op: "write", Path: name, Err: err}
+		}
+	}
+	addLinks, removeLinks, err := linkChanges(old, msg.Header, types)
+	if err != nil {
+		return &fs.PathError{Op: "write", Path: name, Err: err}
+	}
+	defer fsys.Cache.Invalidate(key)
+	if len(fields) > 0 {
+		if err := fsys.Client.UpdateIssueContext(fsys.context(), key, fields); err != nil {
+			return &fs.PathError{Op: "write", Path: name, Err: err}
+		}
+	}
+	if status != "" {
+		if err := fsys.Client.TransitionContext(fsys.context(), key, status); err != nil {
+			return &fs.PathError{Op: "write", Path: name, Err: err}
+		}
+	}
+	for _, l := range removeLinks {
+		if err := fsys.Client.UnlinkContext(fsys.context(), l.id); err != nil {
+			return &fs.PathError{Op: "write", Path: name, Err: fmt.Errorf("unlink %s: %w", l.key, err)}
+		}
+	}
+	for _, l := range addLinks {
+		from, to := key, l.key
+		if !l.outward {
+			from, to = to, from
+		}
+		if err := fsys.Client.LinkContext(fsys.context(), l.typ.Name, from, to); err != nil {
+			return &fs.PathError{Op: "write", Path: name, Err: fmt.Errorf("link %s: %w", l.key, err)}
+		}
+	}
+	return nil
+}
+
+// issueChanges returns the fields of msg, an issue file,
+// which differ from old, ready to pass to Client.UpdateIssue.
+// If the status differs from old, it is returned as status.
+// Raw reports whether the description in msg is in Jira text formatting
+// rather than plain text.
+// Custom holds the fields shown as headers by printIssue.
+// Lookup finds the users named in user fields, as Client.LookupUser.
+func issueChanges(old *Issue, msg *mail.Message, raw bool, custom []Field, lookup func(string) (*User, error)) (fields map[string]any, status string, err error) {
+	fields = make(map[string]any)
+
+	subject := strings.TrimSpace(msg.Header.Get("Subject"))
+	if subject == "" {
+		return nil, "", errors.New("empty subject")
+	} else if subject != old.Summary {
+		fields["summary"] = subject
+	}
+
+	assignee := strings.TrimSpace(msg.Header.Get("Assignee"))
+	if assignee != old.Assignee.String() {
+		if assignee == "" {
+			fields["assignee"] = nil
+		} else {
+			u, err := lookup(assignee)
+			if err != nil {
+				return nil, "", fmt.Errorf("assignee: %w", err)
+			}
+			fields["assignee"] = u.ref()
+		}
+	}
+
+	for _, f := range custom {
+		v, ok := msg.Header[textproto.CanonicalMIMEHeaderKey(headerName(f))]
+		if !ok || strings.TrimSpace(v[0]) == formatField(f, old.Extra[f.ID]) {
+			continue
+		}
+		fields[f.ID], err = fieldValue(f, v[0], lookup)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	status = strings.TrimSpace(msg.Header.Get("Status"))
+	if status == old.Status.Name {
+		status = ""
+	}
+
+	b, err := io.ReadAll(msg.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("read body: %w", err)
+	}
+	body := strings.TrimRight(string(b), "\n")
+	body = strings.TrimSuffix(body, strings.TrimRight(printCommentList(old.Comments), "\n"))
+	body = strings.Trim(body, "\n")
+	if body != printBody(old.Description, raw) {
+		if !raw {
+			body = ToJTF(body)
+		}
+		fields["description"] = body
+	}
+	return fields, status, nil
+}
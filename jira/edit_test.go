@@ -0,0 +1,274 @@
+package jira_test
+
+import (
+	"encoding/json"
+	"io"
+	"io/fs"
+	"slices"
+	"strings"
+	"testing"
+
+	"olowe.co/issues/jira"
+)
+
+func TestWriteFile(t *testing.T) {
+	srv, client := newServer(t)
+	fsys := &jira.FS{Client: client}
+
+	f, err := fsys.Open("TEST/1/issue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(f)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	old, err := fsys.Client.Issue("TEST-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantSummary := "A brand new summary"
+	wantDescription := "Nothing to see here."
+	s := strings.Replace(string(b), "Subject: "+old.Summary, "Subject: "+wantSummary, 1)
+	s = strings.Replace(s, jira.FromJTF(old.Description), wantDescription, 1)
+	s = strings.Replace(s, "Status: "+old.Status.Name, "Status: In Progress", 1)
+	if err := fsys.WriteFile("TEST/1/issue", []byte(s)); err != nil {
+		t.Fatal(err)
+	}
+
+	issue, err := fsys.Client.Issue("TEST-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issue.Summary != wantSummary {
+		t.Errorf("summary = %q, want %q", issue.Summary, wantSummary)
+	}
+	if issue.Description != wantDescription {
+		t.Errorf("description = %q, want %q", issue.Description, wantDescription)
+	}
+	if issue.Status.Name != "In Progress" {
+		t.Errorf("status = %q, want %q", issue.Status.Name, "In Progress")
+	}
+	if issue.Assignee != old.Assignee {
+		t.Errorf("assignee changed from %s to %s", old.Assignee, issue.Assignee)
+	}
+
+	// Users are assigned by address, as shown in the file.
+	srv.AddUser(jira.User{Name: "ann", DisplayName: "Ann Jones", Email: "ann@example.com"}, "secret")
+	s = strings.Replace(s, "Assignee: "+old.Assignee.String(), "Assignee: Ann Jones <ann@example.com>", 1)
+	if err := fsys.WriteFile("TEST/1/issue", []byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	issue, err = fsys.Client.Issue("TEST-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issue.Assignee.Name != "ann" {
+		t.Errorf("assigned to %q, want ann", issue.Assignee.Name)
+	}
+	bad := strings.Replace(s, "Assignee: Ann Jones <ann@example.com>", "Assignee: nobody@example.com", 1)
+	if err := fsys.WriteFile("TEST/1/issue", []byte(bad)); err == nil {
+		t.Error("no error assigning unknown address")
+	}
+
+	if err := fsys.WriteFile("TEST/1/"+commentID(t, client, "TEST-1"), []byte(s)); err == nil {
+		t.Error("nil error writing to comment file")
+	}
+}
+
+func TestWriteCustomFields(t *testing.T) {
+	srv, client := newServer(t)
+	points := srv.AddField("Story Points", jira.FieldSchema{Type: "number"})
+	sprint := srv.AddField("Sprint", jira.FieldSchema{Type: "array", Items: "json", Custom: "com.pyxis.greenhopper.jira:gh-sprint"})
+	team := srv.AddField("Team", jira.FieldSchema{Type: "option"})
+	srv.AddIssue("TEST", map[string]any{
+		"summary": "Out of paper",
+		points:    3,
+		sprint:    []string{"com.atlassian.greenhopper.service.sprint.Sprint@5c3e2f1[id=12,state=ACTIVE,name=Sprint 12,goal=]"},
+		team:      nil,
+	})
+	fsys := &jira.FS{Client: client, CustomFields: []string{"Story Points", sprint, "team"}}
+
+	b, err := fs.ReadFile(fsys, "TEST/2/issue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Story-Points: 3\n", "Sprint: Sprint 12\n", "Team: \n"} {
+		if !strings.Contains(string(b), want) {
+			t.Errorf("issue file missing header %q", want)
+		}
+	}
+
+	s := strings.Replace(string(b), "Story-Points: 3", "Story-Points: 5", 1)
+	s = strings.Replace(s, "Team: ", "Team: Platform", 1)
+	if err := fsys.WriteFile("TEST/2/issue", []byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	issue, err := fsys.Client.Issue("TEST-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(issue.Extra[points]); got != "5" {
+		t.Errorf("story points = %s, want 5", got)
+	}
+	if got := string(issue.Extra[team]); got != `{"value":"Platform"}` {
+		t.Errorf("team = %s, want Platform", got)
+	}
+
+	// Sprints are set by their ID, not by name.
+	s = strings.Replace(s, "Sprint: Sprint 12", "Sprint: Sprint 13", 1)
+	if err := fsys.WriteFile("TEST/2/issue", []byte(s)); err == nil {
+		t.Error("no error changing sprint by name")
+	}
+
+	fsys = &jira.FS{Client: fsys.Client, CustomFields: []string{"Flavour"}}
+	if _, err := fsys.Open("TEST/2/issue"); err == nil {
+		t.Error("no error opening filesystem with unknown custom field")
+	}
+}
+
+func TestWriteLinks(t *testing.T) {
+	srv, client := newServer(t)
+	blocked := srv.AddIssue("TEST", map[string]any{"summary": "Out of paper"})
+	if err := client.Link("Blocks", "TEST-1", blocked); err != nil {
+		t.Fatal(err)
+	}
+	fsys := &jira.FS{Client: client}
+	created, err := fsys.Client.CreateIssue(&jira.Issue{Project: jira.Project{Key: "TEST"}, Summary: "clone"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := fs.ReadFile(fsys, "TEST/1/issue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), "Blocks: "+blocked+"\n") {
+		t.Fatalf("issue file missing link header:\n%s", b)
+	}
+	s := strings.Replace(string(b), "Blocks: "+blocked, "Blocks:\nIs-Cloned-By: "+created.Key, 1)
+	if err := fsys.WriteFile("TEST/1/issue", []byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	issue, err := fsys.Client.Issue("TEST-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issue.Links) != 1 {
+		t.Errorf("got %d links, want 1", len(issue.Links))
+	}
+	var cloneLink jira.Link
+	for _, l := range issue.Links {
+		switch {
+		case l.Relation() == "blocks":
+			t.Errorf("link %s blocks %s not removed", l.ID, l.Issue.Key)
+		case l.Relation() == "is cloned by" && l.Issue.Key == created.Key:
+			cloneLink = l
+		}
+	}
+	if cloneLink.ID == "" {
+		t.Errorf("TEST-1 not linked as cloned by %s", created.Key)
+	}
+	clone, err := fsys.Client.Issue(created.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(clone.Links) != 1 || clone.Links[0].Relation() != "clones" || clone.Links[0].Issue.Key != "TEST-1" {
+		t.Errorf("got links of %s %+v, want only clones TEST-1", created.Key, clone.Links)
+	}
+
+	// Unchanged links are left alone.
+	if err := fsys.WriteFile("TEST/1/issue", []byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	again, err := fsys.Client.Issue("TEST-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(again.Links) != len(issue.Links) || !slices.ContainsFunc(again.Links, func(l jira.Link) bool { return l.ID == cloneLink.ID }) {
+		t.Errorf("rewriting unchanged links changed them: got %d links", len(again.Links))
+	}
+
+	// So are links whose header is removed,
+	// without fetching the link types.
+	b, err = fs.ReadFile(fsys, "TEST/1/issue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := record(fsys.Client)
+	missing := strings.Replace(string(b), "Is-Cloned-By: "+created.Key+"\n", "", 1)
+	if missing == string(b) {
+		t.Fatalf("issue file missing link header:\n%s", b)
+	}
+	if err := fsys.WriteFile("TEST/1/issue", []byte(missing)); err != nil {
+		t.Fatal(err)
+	}
+	for _, req := range rec.take() {
+		if strings.HasSuffix(req.URL.Path, "/issueLinkType") {
+			t.Errorf("link types fetched writing unchanged links")
+		}
+	}
+	again, err = fsys.Client.Issue("TEST-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(again.Links) != 1 || again.Links[0].ID != cloneLink.ID {
+		t.Errorf("removing link header changed links: got %+v", again.Links)
+	}
+
+	for _, header := range []string{"Relates-To: TEST-1", "Relates-To: TEST-999"} {
+		bad := strings.Replace(s, "Subject:", header+"\nSubject:", 1)
+		if err := fsys.WriteFile("TEST/1/issue", []byte(bad)); err == nil {
+			t.Errorf("no error writing %q", header)
+		}
+	}
+}
+
+func TestWriteUserField(t *testing.T) {
+	srv, client := newServer(t)
+	ann := jira.User{Name: "ann", DisplayName: "Ann Jones", Email: "ann@example.com"}
+	srv.AddUser(ann, "secret")
+	reviewer := srv.AddField("Reviewer", jira.FieldSchema{Type: "user"})
+	points := srv.AddField("Story Points", jira.FieldSchema{Type: "number"})
+	key := srv.AddIssue("TEST", map[string]any{"summary": "Out of paper", reviewer: fred, points: 1})
+	name := strings.Replace(key, "-", "/", 1) + "/issue"
+	fsys := &jira.FS{Client: client, CustomFields: []string{"Reviewer", "Story Points"}}
+
+	b, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Reviewer: " + fred.String() + "\n"; !strings.Contains(string(b), want) {
+		t.Fatalf("issue file missing header %q:\n%s", want, b)
+	}
+	// Users are shown in the form they are looked up by,
+	// so the header finds the same user when written back.
+	// Change the case of the address to make sure it is looked up.
+	s := strings.Replace(string(b), "Story-Points: 1", "Story-Points: 2", 1)
+	s = strings.Replace(s, "Reviewer: "+fred.String(), "Reviewer: Fred Smith <FRED@EXAMPLE.COM>", 1)
+	if err := fsys.WriteFile(name, []byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	issue, err := fsys.Client.Issue(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got jira.User
+	if err := json.Unmarshal(issue.Extra[reviewer], &got); err != nil || got.Name != fred.Name {
+		t.Errorf("reviewer after rewrite = %s, want %s", issue.Extra[reviewer], fred.Name)
+	}
+
+	s = strings.Replace(s, "Reviewer: Fred Smith <FRED@EXAMPLE.COM>", "Reviewer: "+ann.String(), 1)
+	if err := fsys.WriteFile(name, []byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	b, err = fs.ReadFile(fsys, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Reviewer: " + ann.String() + "\n"; !strings.Contains(string(b), want) {
+		t.Errorf("issue file missing header %q after edit:\n%s", want, b)
+	}
+}
@@ -0,0 +1,102 @@
+package jira
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Errors matched by an *Error, for use with errors.Is.
+var (
+	// ErrNotFound matches responses with status 404 Not Found.
+	ErrNotFound = errors.New("not found")
+	// ErrUnauthorized matches responses with status
+	// 401 Unauthorized or 403 Forbidden.
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrRateLimited matches responses with status 429 Too Many Requests.
+	ErrRateLimited = errors.New("rate limited")
+)
+
+// Error is returned by Client methods when Jira responds
+// with an error status.
+type Error struct {
+	StatusCode int
+	Status     string // such as "400 Bad Request"
+	Method     string
+	URL        string
+	// Messages holds the errorMessages reported by Jira.
+	Messages []string
+	// Fields maps the names of fields to the error reported for each,
+	// such as a query's jql parameter.
+	Fields map[string]string
+}
+
+func (e *Error) Error() string {
+	s := fmt.Sprintf("%s %s: %s", e.Method, e.URL, e.Status)
+	if reasons := e.reasons(); len(reasons) > 0 {
+		s += ": " + strings.Join(reasons, "; ")
+	}
+	return s
+}
+
+// reasons returns Jira's messages followed by the field errors
+// in order of field name.
+func (e *Error) reasons() []string {
+	reasons := slices.Clone(e.Messages)
+	names := make([]string, 0, len(e.Fields))
+	for k := range e.Fields {
+		names = append(names, k)
+	}
+	slices.Sort(names)
+	for _, k := range names {
+		reasons = append(reasons, k+": "+e.Fields[k])
+	}
+	return reasons
+}
+
+// Is reports whether target is the sentinel error
+// corresponding to the error's status code.
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	}
+	return false
+}
+
+// maxErrorBody is the most we read of an error response body.
+const maxErrorBody = 1 << 20
+
+// responseError returns an *Error describing resp,
+// including any error messages in the response body.
+// The body is not closed.
+func responseError(resp *http.Response) error {
+	e := &Error{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+	}
+	if resp.Request != nil {
+		e.Method = resp.Request.Method
+		e.URL = resp.Request.URL.String()
+	}
+	var body struct {
+		ErrorMessages []string          `json:"errorMessages"`
+		Errors        map[string]string `json:"errors"`
+	}
+	b, err := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	if err == nil && json.Unmarshal(b, &body) == nil {
+		e.Messages = body.ErrorMessages
+		if len(body.Errors) > 0 {
+			e.Fields = body.Errors
+		}
+	}
+	return e
+}
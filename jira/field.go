@@ -0,0 +1,256 @@
+package jira
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Field describes an issue field, such as "summary" or a custom field.
+type Field struct {
+	// ID is the key of the field in issues, such as "customfield_10016".
+	ID     string      `json:"id"`
+	Name   string      `json:"name"` // such as "Story Points"
+	Custom bool        `json:"custom"`
+	Schema FieldSchema `json:"schema"`
+}
+
+// FieldSchema describes the type of a field's values.
+type FieldSchema struct {
+	// Type is the type of the value, such as "string", "number",
+	// "option", "user" or "array".
+	Type string `json:"type"`
+	// Items is the type of each element of an array.
+	Items string `json:"items,omitempty"`
+	// Custom identifies the plugin providing a custom field,
+	// such as "com.pyxis.greenhopper.jira:gh-sprint".
+	Custom string `json:"custom,omitempty"`
+}
+
+// FieldRegistry maps between the names and IDs of the fields
+// known to a Jira server.
+type FieldRegistry struct {
+	fields []Field
+	byID   map[string]int
+	byName map[string]int
+}
+
+// NewFieldRegistry returns a registry of fields.
+// Where fields share a name, the first is found by name.
+func NewFieldRegistry(fields []Field) *FieldRegistry {
+	r := &FieldRegistry{
+		fields: fields,
+		byID:   make(map[string]int),
+		byName: make(map[string]int),
+	}
+	for i, f := range fields {
+		r.byID[f.ID] = i
+		if _, ok := r.byName[strings.ToLower(f.Name)]; !ok {
+			r.byName[strings.ToLower(f.Name)] = i
+		}
+	}
+	return r
+}
+
+// Lookup returns the field with the given ID, such as "customfield_10016",
+// or otherwise the field with the given name, such as "Story Points",
+// ignoring case.
+func (r *FieldRegistry) Lookup(s string) (Field, bool) {
+	if i, ok := r.byID[s]; ok {
+		return r.fields[i], true
+	}
+	if i, ok := r.byName[strings.ToLower(s)]; ok {
+		return r.fields[i], true
+	}
+	return Field{}, false
+}
+
+// Fields returns every field in the registry.
+func (r *FieldRegistry) Fields() []Field {
+	return r.fields
+}
+
+// Fields returns a registry of the issue fields known to Jira,
+// both built-in and custom.
+func (c *Client) Fields() (*FieldRegistry, error) {
+	return c.FieldsContext(context.Background())
+}
+
+// FieldsContext is like Fields, with requests made using ctx.
+func (c *Client) FieldsContext(ctx context.Context) (*FieldRegistry, error) {
+	u := *c.APIRoot
+	u.Path = path.Join(u.Path, "field")
+	b, err := c.getJSON(ctx, u.String())
+	if err != nil {
+		return nil, err
+	}
+	var fields []Field
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, fmt.Errorf("decode fields: %w", err)
+	}
+	return NewFieldRegistry(fields), nil
+}
+
+// lookupFields returns the fields of the registry named by names,
+// which may be field names or IDs.
+// Fields decoded into Issue, which have their own headers, are not allowed.
+func lookupFields(r *FieldRegistry, names []string) ([]Field, error) {
+	fields := make([]Field, len(names))
+	for i, name := range names {
+		f, ok := r.Lookup(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown field %q", name)
+		}
+		if decodedFields[f.ID] {
+			return nil, fmt.Errorf("field %q is always shown", name)
+		}
+		fields[i] = f
+	}
+	return fields, nil
+}
+
+// headerName returns the name of the header holding the field in an issue file.
+// Spaces and other characters not allowed in header names become dashes,
+// so "Story Points" becomes "Story-Points".
+func headerName(f Field) string {
+	name := strings.Map(func(r rune) rune {
+		if r < 128 && (r == '-' || 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9') {
+			return r
+		}
+		return '-'
+	}, strings.TrimSpace(f.Name))
+	for strings.Contains(name, "--") {
+		name = strings.ReplaceAll(name, "--", "-")
+	}
+	name = strings.Trim(name, "-")
+	if name == "" {
+		return f.ID
+	}
+	return name
+}
+
+// sprintName matches the name in the string form of sprints
+// returned by Jira Server, such as
+// "com.atlassian.greenhopper.service.sprint.Sprint@1a2b[id=1,state=ACTIVE,name=Sprint 1,...]".
+var sprintName = regexp.MustCompile(`[\[,]name=([^,\]]*)`)
+
+// formatField returns the text of the JSON value v of field f
+// as shown in a header.
+// Users are shown as by User.String, the form accepted by Client.LookupUser.
+// Other objects are shown by their name or value, and arrays
+// by their elements separated by commas.
+// Null values are shown as an empty string.
+func formatField(f Field, v json.RawMessage) string {
+	if f.Schema.Type == "user" || f.Schema.Type == "array" && f.Schema.Items == "user" {
+		var users []User
+		if f.Schema.Type == "user" {
+			var u *User
+			if err := json.Unmarshal(v, &u); err != nil || u == nil {
+				return ""
+			}
+			users = append(users, *u)
+		} else if err := json.Unmarshal(v, &users); err != nil {
+			return ""
+		}
+		s := make([]string, 0, len(users))
+		for _, u := range users {
+			if u.String() != "" {
+				s = append(s, u.String())
+			}
+		}
+		return strings.Join(s, ", ")
+	}
+	var x any
+	if err := json.Unmarshal(v, &x); err != nil {
+		return ""
+	}
+	return formatValue(x)
+}
+
+func formatValue(x any) string {
+	switch x := x.(type) {
+	case string:
+		if strings.HasPrefix(x, "com.atlassian.greenhopper.service.sprint.Sprint") {
+			if m := sprintName.FindStringSubmatch(x); m != nil {
+				return m[1]
+			}
+		}
+		return x
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(x)
+	case []any:
+		s := make([]string, 0, len(x))
+		for _, v := range x {
+			if v := formatValue(v); v != "" {
+				s = append(s, v)
+			}
+		}
+		return strings.Join(s, ", ")
+	case map[string]any:
+		for _, k := range []string{"name", "value", "displayName", "key"} {
+			if v, ok := x[k]; ok {
+				return formatValue(v)
+			}
+		}
+		if v, ok := x["id"]; ok {
+			return formatValue(v)
+		}
+	}
+	return ""
+}
+
+// fieldValue returns the value to send to Jira to set the field to s,
+// as formatted by formatField.
+// An empty s clears the field.
+// Users are found by lookup, as Client.LookupUser.
+func fieldValue(f Field, s string, lookup func(string) (*User, error)) (any, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	if f.Schema.Type != "array" {
+		return scalarValue(f.Schema.Type, f.Name, s, lookup)
+	}
+	var values []any
+	for _, elem := range strings.Split(s, ",") {
+		if elem = strings.TrimSpace(elem); elem == "" {
+			continue
+		}
+		v, err := scalarValue(f.Schema.Items, f.Name, elem, lookup)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+func scalarValue(typ, name, s string, lookup func(string) (*User, error)) (any, error) {
+	switch typ {
+	case "string", "date", "datetime":
+		return s, nil
+	case "number":
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %q is not a number", name, s)
+		}
+		return n, nil
+	case "option":
+		return map[string]string{"value": s}, nil
+	case "user":
+		u, err := lookup(s)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", name, err)
+		}
+		return u.ref(), nil
+	case "version", "component", "priority", "group":
+		return map[string]string{"name": s}, nil
+	}
+	return nil, fmt.Errorf("field %s: cannot set values of type %s", name, typ)
+}
@@ -0,0 +1,116 @@
+package jira
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestFields(t *testing.T) {
+	b, err := os.ReadFile("testdata/field")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields []Field
+	if err := json.Unmarshal(b, &fields); err != nil {
+		t.Fatal(err)
+	}
+	reg := NewFieldRegistry(fields)
+	for _, s := range []string{"Story Points", "story points", "customfield_10016"} {
+		f, ok := reg.Lookup(s)
+		if !ok || f.ID != "customfield_10016" || f.Name != "Story Points" || !f.Custom {
+			t.Errorf("lookup %q: got %+v, %v", s, f, ok)
+		}
+	}
+	if _, ok := reg.Lookup("Flavour"); ok {
+		t.Error("found unknown field")
+	}
+	if _, err := lookupFields(reg, []string{"Priority"}); err == nil {
+		t.Error("no error showing decoded field as a custom header")
+	}
+}
+
+func TestFormatField(t *testing.T) {
+	user := FieldSchema{Type: "user"}
+	users := FieldSchema{Type: "array", Items: "user"}
+	var tests = []struct {
+		schema FieldSchema
+		json   string
+		want   string
+	}{
+		{FieldSchema{}, `null`, ""},
+		{FieldSchema{}, `"hello"`, "hello"},
+		{FieldSchema{}, `3`, "3"},
+		{FieldSchema{}, `2.5`, "2.5"},
+		{FieldSchema{}, `{"self": "x", "value": "Platform", "id": "10001"}`, "Platform"},
+		{FieldSchema{}, `{"name": "fred", "displayName": "Fred"}`, "fred"},
+		{FieldSchema{}, `[{"value": "a"}, {"value": "b"}]`, "a, b"},
+		{FieldSchema{}, `[{"id": 12, "name": "Sprint 12", "state": "active"}]`, "Sprint 12"},
+		{FieldSchema{}, `["com.atlassian.greenhopper.service.sprint.Sprint@1a[id=12,state=ACTIVE,name=Sprint 12,goal=]"]`, "Sprint 12"},
+		{user, `{"name": "fred", "displayName": "Fred Smith", "emailAddress": "fred@example.com"}`, "Fred Smith <fred@example.com>"},
+		{user, `{"accountId": "5b10ac8d82e05b22cc7d4ef5", "displayName": "Fred Smith"}`, "Fred Smith"},
+		{user, `null`, ""},
+		{users, `[{"displayName": "Fred", "emailAddress": "fred@example.com"}, {"displayName": "Ann", "emailAddress": "ann@example.com"}]`, "Fred <fred@example.com>, Ann <ann@example.com>"},
+	}
+	for _, tt := range tests {
+		if got := formatField(Field{Schema: tt.schema}, json.RawMessage(tt.json)); got != tt.want {
+			t.Errorf("format %s: got %q, want %q", tt.json, got, tt.want)
+		}
+	}
+}
+
+func TestFieldValue(t *testing.T) {
+	lookup := func(s string) (*User, error) {
+		switch s {
+		case "Fred <fred@example.com>":
+			return &User{Name: "fred", Email: "fred@example.com"}, nil
+		case "Ann <ann@example.com>":
+			return &User{AccountID: "5b10a2844c20165700ede21g", Email: "ann@example.com"}, nil
+		}
+		return nil, fmt.Errorf("no user %s", s)
+	}
+	var tests = []struct {
+		schema FieldSchema
+		s      string
+		want   any
+	}{
+		{FieldSchema{Type: "number"}, "5", 5.0},
+		{FieldSchema{Type: "string"}, " hello ", "hello"},
+		{FieldSchema{Type: "option"}, "Platform", map[string]string{"value": "Platform"}},
+		{FieldSchema{Type: "user"}, "Fred <fred@example.com>", map[string]string{"name": "fred"}},
+		{FieldSchema{Type: "array", Items: "user"}, "Ann <ann@example.com>", []any{map[string]string{"accountId": "5b10a2844c20165700ede21g"}}},
+		{FieldSchema{Type: "array", Items: "string"}, "a, b,", []any{"a", "b"}},
+		{FieldSchema{Type: "number"}, "", nil},
+	}
+	for _, tt := range tests {
+		got, err := fieldValue(Field{Name: "test", Schema: tt.schema}, tt.s, lookup)
+		if err != nil {
+			t.Errorf("%s value %q: %v", tt.schema.Type, tt.s, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s value %q: got %#v, want %#v", tt.schema.Type, tt.s, got, tt.want)
+		}
+	}
+	for _, schema := range []FieldSchema{{Type: "number"}, {Type: "array", Items: "json"}, {Type: "user"}} {
+		if _, err := fieldValue(Field{Name: "test", Schema: schema}, "abc", lookup); err == nil {
+			t.Errorf("%+v value abc: no error", schema)
+		}
+	}
+}
+
+func TestHeaderName(t *testing.T) {
+	for name, want := range map[string]string{
+		"Story Points":  "Story-Points",
+		"Team (new)":    "Team-new",
+		"???":           "customfield_1",
+		"Start date ":   "Start-date",
+		"Fix-Version/s": "Fix-Version-s",
+	} {
+		if got := headerName(Field{ID: "customfield_1", Name: name}); got != want {
+			t.Errorf("header for %q: got %q, want %q", name, got, want)
+		}
+	}
+}
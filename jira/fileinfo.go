@@ -14,14 +14,14 @@ func (is *Issue) Name() string {
 	return number
 }
 
-func (is *Issue) Size() int64        { return int64(len(printIssue(is))) }
+func (is *Issue) Size() int64        { return int64(len(printIssue(is, false, nil))) }
 func (is *Issue) Mode() fs.FileMode  { return 0o444 | fs.ModeDir }
 func (is *Issue) ModTime() time.Time { return is.Updated }
 func (is *Issue) IsDir() bool        { return is.Mode().IsDir() }
 func (is *Issue) Sys() any           { return nil }
 
 func (c *Comment) Name() string       { return c.ID }
-func (c *Comment) Size() int64        { return int64(len(printComment(c))) }
+func (c *Comment) Size() int64        { return int64(len(printComment(c, false))) }
 func (c *Comment) Mode() fs.FileMode  { return 0o444 }
 func (c *Comment) ModTime() time.Time { return c.Updated }
 func (c *Comment) IsDir() bool        { return c.Mode().IsDir() }
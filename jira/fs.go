@@ -1,6 +1,8 @@
 package jira
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
@@ -12,7 +14,42 @@ import (
 
 type FS struct {
 	Client *Client
-	root   *fid
+	// Raw, if true, presents descriptions and comments in
+	// Jira text formatting instead of converting them to plain text.
+	// It may be changed in a copy of an FS, such as to toggle
+	// between formats, without fetching the projects again.
+	Raw bool
+	// Cache, if not nil, holds issues fetched by the FS for reuse.
+	// The cache may be shared with other FS values.
+	Cache *Cache
+	// CustomFields names fields, such as "Story Points" or "customfield_10016",
+	// shown as headers in issue files after the built-in headers.
+	// Each header is named after its field, with spaces replaced by dashes,
+	// as in "Story-Points: 3".
+	// Changes to these headers are applied to the issue by WriteFile.
+	// The names are looked up using Client.Fields when the FS is first used.
+	CustomFields []string
+	root         *fid
+	ctx          context.Context
+}
+
+// WithContext returns a shallow copy of fsys whose requests are made using ctx.
+// Once ctx is done, operations on the copy and on files opened from it
+// fail with ctx's error.
+func (fsys *FS) WithContext(ctx context.Context) *FS {
+	if ctx == nil {
+		panic("nil context")
+	}
+	fsys2 := *fsys
+	fsys2.ctx = ctx
+	return &fsys2
+}
+
+func (fsys *FS) context() context.Context {
+	if fsys.ctx != nil {
+		return fsys.ctx
+	}
+	return context.Background()
 }
 
 const (
@@ -21,12 +58,20 @@ const (
 	ftypeIssue
 	ftypeIssueDir
 	ftypeComment
+	ftypeThread
+	ftypeHistory
+	ftypeAttachmentDir
+	ftypeAttachment
 )
 
 type fid struct {
 	*Client
+	ctx    context.Context
+	cache  *Cache
 	name   string
 	typ    int
+	raw    bool
+	fields []Field // custom fields shown in issue files
 	rd     io.Reader
 	parent *fid
 
@@ -43,7 +88,7 @@ func (f *fid) IsDir() bool  { return f.Type().IsDir() }
 
 func (f *fid) Type() fs.FileMode {
 	switch f.typ {
-	case ftypeRoot, ftypeProject, ftypeIssueDir:
+	case ftypeRoot, ftypeProject, ftypeIssueDir, ftypeAttachmentDir:
 		return fs.ModeDir
 	}
 	return 0
@@ -63,34 +108,59 @@ func (f *fid) Stat() (fs.FileInfo, error) {
 	case ftypeRoot:
 		return &stat{".", int64(len(f.children)), 0o444 | fs.ModeDir, time.Time{}}, nil
 	case ftypeProject:
-		p, err := f.Project(f.name)
+		p, err := f.ProjectContext(f.ctx, f.name)
 		if err != nil {
-			return nil, &fs.PathError{"stat", f.name, err}
+			return nil, &fs.PathError{Op: "stat", Path: f.name, Err: fsErr(err)}
 		}
 		return p, nil
-	case ftypeIssueDir, ftypeIssue:
-		is, err := f.Issue(f.issueKey())
+	case ftypeIssueDir, ftypeIssue, ftypeThread:
+		is, err := f.fetchIssue()
 		if err != nil {
-			return nil, &fs.PathError{"stat", f.name, err}
+			return nil, &fs.PathError{Op: "stat", Path: f.name, Err: fsErr(err)}
 		}
 		if f.typ == ftypeIssueDir {
 			f.children = issueChildren(f, is)
 			return is, nil
 		}
 		// optimisation: we might read the file soon so load the contents.
-		f.rd = strings.NewReader(printIssue(is))
-		return &stat{f.name, int64(len(printIssue(is))), 0o444, is.Updated}, nil
+		s := printIssue(is, f.raw, f.fields)
+		if f.typ == ftypeThread {
+			s = printThread(is, f.raw)
+		}
+		f.rd = strings.NewReader(s)
+		return &stat{f.name, int64(len(s)), 0o444, is.Updated}, nil
 	case ftypeComment:
-		c, err := f.Comment(f.issueKey(), f.name)
+		c, err := f.fetchComment()
 		if err != nil {
-			return nil, &fs.PathError{"stat", f.name, err}
+			return nil, &fs.PathError{Op: "stat", Path: f.name, Err: fsErr(err)}
 		}
 		// optimisation: we might read the file soon so load the contents.
-		f.rd = strings.NewReader(printComment(c))
-		return c, nil
+		f.rd = strings.NewReader(printComment(c, f.raw))
+		return commentStat(c, f.raw), nil
+	case ftypeHistory:
+		changes, err := f.ChangelogContext(f.ctx, f.issueKey())
+		if err != nil {
+			return nil, &fs.PathError{Op: "stat", Path: f.name, Err: fsErr(err)}
+		}
+		s := printHistory(changes)
+		f.rd = strings.NewReader(s)
+		return historyStat(changes, s), nil
+	case ftypeAttachmentDir:
+		is, err := f.fetchIssue()
+		if err != nil {
+			return nil, &fs.PathError{Op: "stat", Path: f.name, Err: fsErr(err)}
+		}
+		f.children = attachmentChildren(f, is)
+		return &stat{f.name, int64(len(f.children)), 0o444 | fs.ModeDir, is.Updated}, nil
+	case ftypeAttachment:
+		a, err := f.fetchAttachment()
+		if err != nil {
+			return nil, &fs.PathError{Op: "stat", Path: f.name, Err: fsErr(err)}
+		}
+		return attachmentStat(f.name, a), nil
 	}
 	err := fmt.Errorf("unexpected fid type %d", f.typ)
-	return nil, &fs.PathError{"stat", f.name, err}
+	return nil, &fs.PathError{Op: "stat", Path: f.name, Err: err}
 }
 
 func (f *fid) Read(p []byte) (n int, err error) {
@@ -100,25 +170,47 @@ func (f *fid) Read(p []byte) (n int, err error) {
 	if f.rd == nil {
 		switch f.typ {
 		case ftypeComment:
-			c, err := f.Comment(f.issueKey(), f.name)
+			c, err := f.fetchComment()
 			if err != nil {
-				err = fmt.Errorf("get comment %s: %w", f.issueKey(), err)
-				return 0, &fs.PathError{"read", f.name, err}
+				return 0, &fs.PathError{Op: "read", Path: f.name, Err: fsErr(err)}
 			}
-			f.rd = strings.NewReader(printComment(c))
+			f.rd = strings.NewReader(printComment(c, f.raw))
 		case ftypeIssue:
-			is, err := f.Issue(f.issueKey())
+			is, err := f.fetchIssue()
+			if err != nil {
+				return 0, &fs.PathError{Op: "read", Path: f.name, Err: fsErr(err)}
+			}
+			f.rd = strings.NewReader(printIssue(is, f.raw, f.fields))
+		case ftypeThread:
+			is, err := f.fetchIssue()
 			if err != nil {
-				err = fmt.Errorf("get issue %s: %w", f.issueKey(), err)
-				return 0, &fs.PathError{"read", f.name, err}
+				return 0, &fs.PathError{Op: "read", Path: f.name, Err: fsErr(err)}
 			}
-			f.rd = strings.NewReader(printIssue(is))
+			f.rd = strings.NewReader(printThread(is, f.raw))
+		case ftypeHistory:
+			changes, err := f.ChangelogContext(f.ctx, f.issueKey())
+			if err != nil {
+				return 0, &fs.PathError{Op: "read", Path: f.name, Err: fsErr(err)}
+			}
+			f.rd = strings.NewReader(printHistory(changes))
+		case ftypeAttachment:
+			a, err := f.fetchAttachment()
+			if err != nil {
+				return 0, &fs.PathError{Op: "read", Path: f.name, Err: fsErr(err)}
+			}
+			// Stream the content rather than holding it in memory;
+			// attachments may be large.
+			rc, err := f.OpenAttachmentContext(f.ctx, a)
+			if err != nil {
+				return 0, &fs.PathError{Op: "read", Path: f.name, Err: fsErr(err)}
+			}
+			f.rd = rc
 		default:
 			var err error
 			if f.children == nil {
 				f.children, err = f.ReadDir(-1)
 				if err != nil {
-					return 0, &fs.PathError{"read", f.name, err}
+					return 0, &fs.PathError{Op: "read", Path: f.name, Err: err}
 				}
 			}
 			buf := &strings.Builder{}
@@ -132,9 +224,13 @@ func (f *fid) Read(p []byte) (n int, err error) {
 }
 
 func (f *fid) Close() error {
+	var err error
+	if c, ok := f.rd.(io.Closer); ok {
+		err = c.Close()
+	}
 	f.rd = nil
 	f.stat = nil
-	return nil
+	return err
 }
 
 func (f *fid) ReadDir(n int) ([]fs.DirEntry, error) {
@@ -149,25 +245,35 @@ func (f *fid) ReadDir(n int) ([]fs.DirEntry, error) {
 		case ftypeRoot:
 			return nil, fmt.Errorf("root initialised incorrectly: no dir entries")
 		case ftypeProject:
-			issues, err := f.Issues(f.name)
+			issues, err := f.fetchIssues()
 			if err != nil {
-				return nil, fmt.Errorf("get issues: %w", err)
+				return nil, &fs.PathError{Op: "readdir", Path: f.name, Err: fsErr(err)}
 			}
 			f.children = make([]fs.DirEntry, len(issues))
 			for i, issue := range issues {
 				f.children[i] = &fid{
 					Client: f.Client,
+					ctx:    f.ctx,
+					cache:  f.cache,
+					raw:    f.raw,
+					fields: f.fields,
 					name:   issue.Name(),
 					typ:    ftypeIssueDir,
 					parent: f,
 				}
 			}
 		case ftypeIssueDir:
-			issue, err := f.Issue(f.issueKey())
+			issue, err := f.fetchIssue()
 			if err != nil {
-				return nil, fmt.Errorf("get issue %s: %w", f.name, err)
+				return nil, &fs.PathError{Op: "readdir", Path: f.name, Err: fsErr(err)}
 			}
 			f.children = issueChildren(f, issue)
+		case ftypeAttachmentDir:
+			issue, err := f.fetchIssue()
+			if err != nil {
+				return nil, &fs.PathError{Op: "readdir", Path: f.name, Err: fsErr(err)}
+			}
+			f.children = attachmentChildren(f, issue)
 		}
 	}
 
@@ -194,28 +300,203 @@ func (f *fid) ReadDir(n int) ([]fs.DirEntry, error) {
 }
 
 func issueChildren(parent *fid, is *Issue) []fs.DirEntry {
-	kids := make([]fs.DirEntry, len(is.Comments)+1)
+	kids := make([]fs.DirEntry, len(is.Comments)+3, len(is.Comments)+4)
 	for i, c := range is.Comments {
 		kids[i] = &fid{
 			Client: parent.Client,
+			ctx:    parent.ctx,
+			cache:  parent.cache,
 			name:   c.ID,
 			typ:    ftypeComment,
-			rd:     strings.NewReader(printComment(&is.Comments[i])),
+			raw:    parent.raw,
+			fields: parent.fields,
+			rd:     strings.NewReader(printComment(&is.Comments[i], parent.raw)),
 			parent: parent,
-			stat:   &is.Comments[i],
+			stat:   commentStat(&is.Comments[i], parent.raw),
 		}
 	}
-	kids[len(kids)-1] = &fid{
+	s := printIssue(is, parent.raw, parent.fields)
+	kids[len(kids)-3] = &fid{
 		name:   "issue",
 		Client: parent.Client,
+		ctx:    parent.ctx,
+		cache:  parent.cache,
 		typ:    ftypeIssue,
-		rd:     strings.NewReader(is.Summary),
+		raw:    parent.raw,
+		fields: parent.fields,
+		rd:     strings.NewReader(s),
 		parent: parent,
-		stat:   &stat{"issue", int64(len(printIssue(is))), 0o444, is.Updated},
+		stat:   &stat{"issue", int64(len(s)), 0o444, is.Updated},
+	}
+	s = printThread(is, parent.raw)
+	kids[len(kids)-2] = &fid{
+		name:   "thread",
+		Client: parent.Client,
+		ctx:    parent.ctx,
+		cache:  parent.cache,
+		typ:    ftypeThread,
+		raw:    parent.raw,
+		fields: parent.fields,
+		rd:     strings.NewReader(s),
+		parent: parent,
+		stat:   &stat{"thread", int64(len(s)), 0o444, is.Updated},
+	}
+	// The changelog is only requested when the history is used.
+	kids[len(kids)-1] = &fid{
+		name:   "history",
+		Client: parent.Client,
+		ctx:    parent.ctx,
+		cache:  parent.cache,
+		typ:    ftypeHistory,
+		raw:    parent.raw,
+		fields: parent.fields,
+		parent: parent,
+	}
+	if len(is.Attachments) > 0 {
+		kids = append(kids, &fid{
+			name:   "attachments",
+			Client: parent.Client,
+			ctx:    parent.ctx,
+			cache:  parent.cache,
+			typ:    ftypeAttachmentDir,
+			raw:    parent.raw,
+			fields: parent.fields,
+			parent: parent,
+			stat:   &stat{"attachments", int64(len(is.Attachments)), 0o444 | fs.ModeDir, is.Updated},
+		})
 	}
 	return kids
 }
 
+// attachmentChildren returns the entries of the attachments directory
+// of the issue is.
+func attachmentChildren(parent *fid, is *Issue) []fs.DirEntry {
+	names := attachmentNames(is.Attachments)
+	kids := make([]fs.DirEntry, len(is.Attachments))
+	for i := range is.Attachments {
+		kids[i] = &fid{
+			name:   names[i],
+			Client: parent.Client,
+			ctx:    parent.ctx,
+			cache:  parent.cache,
+			typ:    ftypeAttachment,
+			raw:    parent.raw,
+			fields: parent.fields,
+			parent: parent,
+			stat:   attachmentStat(names[i], &is.Attachments[i]),
+		}
+	}
+	return kids
+}
+
+// attachmentStat returns file information for the attachment a
+// held in the named file.
+func attachmentStat(name string, a *Attachment) fs.FileInfo {
+	return &stat{name, a.Size, 0o444, a.Created}
+}
+
+// fetchIssue returns the issue of f, from the cache if f has one.
+func (f *fid) fetchIssue() (*Issue, error) {
+	if f.cache != nil {
+		return f.cache.issue(f.ctx, f.Client, f.issueKey())
+	}
+	return f.IssueContext(f.ctx, f.issueKey())
+}
+
+// fetchAttachment returns the metadata of the attachment held by f,
+// found in its issue.
+func (f *fid) fetchAttachment() (*Attachment, error) {
+	is, err := f.fetchIssue()
+	if err != nil {
+		return nil, err
+	}
+	for i, name := range attachmentNames(is.Attachments) {
+		if name == f.name {
+			return &is.Attachments[i], nil
+		}
+	}
+	return nil, fs.ErrNotExist
+}
+
+// fetchComment returns the comment of f, from the cache if f has one.
+func (f *fid) fetchComment() (*Comment, error) {
+	if f.cache != nil {
+		return f.cache.comment(f.ctx, f.Client, f.issueKey(), f.name)
+	}
+	return f.CommentContext(f.ctx, f.issueKey(), f.name)
+}
+
+// fetchIssues returns the issues in the project of f,
+// from the cache if f has one.
+func (f *fid) fetchIssues() ([]Issue, error) {
+	if f.cache != nil {
+		return f.cache.projectIssues(f.ctx, f.Client, f.name)
+	}
+	return f.IssuesContext(f.ctx, f.name)
+}
+
+// checkIssue reports whether the issue key exists.
+// With a cache, the issue is fetched in full instead,
+// as it is likely to be read soon.
+func (f *fid) checkIssue(key string) (bool, error) {
+	if f.cache == nil {
+		return f.CheckIssueContext(f.ctx, key)
+	}
+	_, err := f.cache.issue(f.ctx, f.Client, key)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// checkIssueComment reports whether the issue directory f
+// holds the comment id.
+func (f *fid) checkIssueComment(id string) (bool, error) {
+	if f.cache == nil {
+		return f.checkComment(f.ctx, f.issueKey(), id)
+	}
+	_, err := f.cache.comment(f.ctx, f.Client, f.issueKey(), id)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// fsErr returns the io/fs equivalent of errors from the Jira API,
+// so that callers may test for them with errors.Is.
+// Issues, comments and projects not found are reported as fs.ErrNotExist,
+// and authorization failures as fs.ErrPermission.
+func fsErr(err error) error {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return fs.ErrNotExist
+	case errors.Is(err, ErrUnauthorized):
+		return fmt.Errorf("%w: %w", fs.ErrPermission, err)
+	}
+	return err
+}
+
+// commentStat returns file information for c.
+// The Comment itself is returned unless the comment is presented raw,
+// as the size of a Comment is that of its plain text form.
+func commentStat(c *Comment, raw bool) fs.FileInfo {
+	if !raw {
+		return c
+	}
+	return &stat{c.ID, int64(len(printComment(c, raw))), 0o444, c.Updated}
+}
+
+// historyStat returns file information for the history file
+// holding s, the printed changes.
+// It was last modified by the most recent change.
+func historyStat(changes []Change, s string) fs.FileInfo {
+	var mtime time.Time
+	if len(changes) > 0 {
+		mtime = changes[len(changes)-1].Created
+	}
+	return &stat{"history", int64(len(s)), 0o444, mtime}
+}
+
 func (f *fid) issueKey() string {
 	// to make the issue key e.g. "EXAMPLE-42"
 	// we need the name of the issue (parent name, "42")
@@ -224,9 +505,12 @@ func (f *fid) issueKey() string {
 	switch f.typ {
 	default:
 		return ""
-	case ftypeComment, ftypeIssue:
+	case ftypeComment, ftypeIssue, ftypeThread, ftypeHistory, ftypeAttachmentDir:
 		project = f.parent.parent.name
 		issueNumber = f.parent.name
+	case ftypeAttachment:
+		project = f.parent.parent.parent.name
+		issueNumber = f.parent.parent.name
 	case ftypeIssueDir:
 		project = f.parent.name
 		issueNumber = f.name
@@ -236,27 +520,23 @@ func (f *fid) issueKey() string {
 
 func (fsys *FS) Open(name string) (fs.File, error) {
 	if !fs.ValidPath(name) {
-		return nil, &fs.PathError{"open", name, fs.ErrInvalid}
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
 	}
 	name = path.Clean(name)
 	if strings.Contains(name, "\\") {
 		return nil, fs.ErrNotExist
 	}
 
-	if fsys.root == nil {
-		var err error
-		fsys.root, err = makeRoot(fsys.Client)
-		if err != nil {
-			return nil, fmt.Errorf("make root file: %w", err)
-		}
+	if err := fsys.init(); err != nil {
+		return nil, err
 	}
 	if fsys.Client.Debug {
 		fmt.Fprintln(os.Stderr, "open", name)
 	}
 
+	root := fsys.root.bind(fsys.context(), fsys.Cache, fsys.Raw)
 	if name == "." {
-		f := *fsys.root
-		return &f, nil
+		return root, nil
 	}
 
 	elems := strings.Split(name, "/")
@@ -264,11 +544,11 @@ func (fsys *FS) Open(name string) (fs.File, error) {
 		elems = elems[1:]
 	}
 
-	f := fsys.root
+	f := root
 	for _, elem := range elems {
 		dir, err := find(f, elem)
 		if err != nil {
-			return nil, &fs.PathError{"open", name, err}
+			return nil, &fs.PathError{Op: "open", Path: name, Err: fsErr(err)}
 		}
 		f = dir
 	}
@@ -276,13 +556,39 @@ func (fsys *FS) Open(name string) (fs.File, error) {
 	return &g, nil
 }
 
-func makeRoot(client *Client) (*fid, error) {
-	projects, err := client.Projects()
+// init makes the root of the filesystem if it has not yet been made.
+func (fsys *FS) init() error {
+	if fsys.root != nil {
+		return nil
+	}
+	var fields []Field
+	if len(fsys.CustomFields) > 0 {
+		reg, err := fsys.Client.FieldsContext(fsys.context())
+		if err != nil {
+			return fmt.Errorf("get fields: %w", err)
+		}
+		fields, err = lookupFields(reg, fsys.CustomFields)
+		if err != nil {
+			return err
+		}
+	}
+	root, err := makeRoot(fsys.context(), fsys.Client, fsys.Raw, fields)
+	if err != nil {
+		return fmt.Errorf("make root file: %w", err)
+	}
+	fsys.root = root
+	return nil
+}
+
+func makeRoot(ctx context.Context, client *Client, raw bool, fields []Field) (*fid, error) {
+	projects, err := client.ProjectsContext(ctx)
 	if err != nil {
 		return nil, err
 	}
 	root := &fid{
 		Client:   client,
+		raw:      raw,
+		fields:   fields,
 		name:     ".",
 		typ:      ftypeRoot,
 		children: make([]fs.DirEntry, len(projects)),
@@ -290,6 +596,8 @@ func makeRoot(client *Client) (*fid, error) {
 	for i, p := range projects {
 		root.children[i] = &fid{
 			Client: client,
+			raw:    raw,
+			fields: fields,
 			name:   p.Key,
 			typ:    ftypeProject,
 		}
@@ -297,11 +605,37 @@ func makeRoot(client *Client) (*fid, error) {
 	return root, nil
 }
 
+// bind returns a copy of f, and of its child entries,
+// which make requests using ctx, keep issues in cache,
+// and present text raw as described by FS.Raw.
+func (f *fid) bind(ctx context.Context, cache *Cache, raw bool) *fid {
+	g := *f
+	g.ctx = ctx
+	g.cache = cache
+	g.raw = raw
+	if f.children == nil {
+		return &g
+	}
+	g.children = make([]fs.DirEntry, len(f.children))
+	for i, d := range f.children {
+		if child, ok := d.(*fid); ok {
+			c := *child
+			c.ctx = ctx
+			c.cache = cache
+			c.raw = raw
+			c.parent = &g
+			d = &c
+		}
+		g.children[i] = d
+	}
+	return &g
+}
+
 func find(dir *fid, name string) (*fid, error) {
 	if !dir.IsDir() {
 		return nil, fs.ErrNotExist
 	}
-	child := &fid{Client: dir.Client, parent: dir}
+	child := &fid{Client: dir.Client, ctx: dir.ctx, cache: dir.cache, raw: dir.raw, fields: dir.fields, parent: dir}
 	switch dir.typ {
 	case ftypeRoot:
 		for _, d := range dir.children {
@@ -316,7 +650,7 @@ func find(dir *fid, name string) (*fid, error) {
 		return nil, fs.ErrNotExist
 	case ftypeProject:
 		key := fmt.Sprintf("%s-%s", dir.name, name)
-		ok, err := dir.CheckIssue(key)
+		ok, err := dir.checkIssue(key)
 		if err != nil {
 			return nil, err
 		}
@@ -332,7 +666,29 @@ func find(dir *fid, name string) (*fid, error) {
 			child.typ = ftypeIssue
 			return child, nil
 		}
-		ok, err := dir.checkComment(dir.issueKey(), name)
+		if name == "thread" {
+			child.name = name
+			child.typ = ftypeThread
+			return child, nil
+		}
+		if name == "history" {
+			child.name = name
+			child.typ = ftypeHistory
+			return child, nil
+		}
+		if name == "attachments" {
+			is, err := dir.fetchIssue()
+			if err != nil {
+				return nil, err
+			}
+			if len(is.Attachments) == 0 {
+				return nil, fs.ErrNotExist
+			}
+			child.name = name
+			child.typ = ftypeAttachmentDir
+			return child, nil
+		}
+		ok, err := dir.checkIssueComment(name)
 		if err != nil {
 			return nil, err
 		} else if !ok {
@@ -341,6 +697,20 @@ func find(dir *fid, name string) (*fid, error) {
 		child.name = name
 		child.typ = ftypeComment
 		return child, nil
+	case ftypeAttachmentDir:
+		is, err := dir.fetchIssue()
+		if err != nil {
+			return nil, err
+		}
+		for i, n := range attachmentNames(is.Attachments) {
+			if n == name {
+				child.name = name
+				child.typ = ftypeAttachment
+				child.stat = attachmentStat(name, &is.Attachments[i])
+				return child, nil
+			}
+		}
+		return nil, fs.ErrNotExist
 	}
 	return nil, fs.ErrNotExist
 }
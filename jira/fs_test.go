@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"io/fs"
 	"os"
+	"slices"
 	"testing"
 )
 
@@ -40,3 +41,36 @@ func TestIssueKey(t *testing.T) {
 		}
 	}
 }
+
+func TestAttachmentNames(t *testing.T) {
+	attachments := []Attachment{
+		{ID: "1", Filename: "log.txt"},
+		{ID: "2", Filename: "screen.png"},
+		{ID: "3", Filename: "log.txt"},
+		{ID: "4", Filename: "a/b.txt"},
+		{ID: "5", Filename: ".."},
+	}
+	want := []string{"1-log.txt", "screen.png", "3-log.txt", "a_b.txt", "5-.."}
+	if got := attachmentNames(attachments); !slices.Equal(got, want) {
+		t.Errorf("got names %q, want %q", got, want)
+	}
+
+	// Names made by replacing slashes or prefixing IDs
+	// may be the real names of other attachments.
+	attachments = []Attachment{
+		{ID: "1", Filename: "a/b.txt"},
+		{ID: "2", Filename: "a_b.txt"},
+		{ID: "3", Filename: "log.txt"},
+		{ID: "4", Filename: "log.txt"},
+		{ID: "5", Filename: "3-log.txt"},
+	}
+	want = []string{"1-a_b.txt", "2-a_b.txt", "3-3-log.txt", "4-log.txt", "5-3-log.txt"}
+	got := attachmentNames(attachments)
+	if !slices.Equal(got, want) {
+		t.Errorf("got names %q, want %q", got, want)
+	}
+	slices.Sort(got)
+	if len(slices.Compact(got)) != len(attachments) {
+		t.Errorf("names %q are not unique", got)
+	}
+}
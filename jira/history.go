@@ -0,0 +1,194 @@
+package jira
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Change is a set of changes made to an issue at once by one user,
+// as recorded in the issue's changelog.
+type Change struct {
+	ID      string       `json:"id"`
+	Author  User         `json:"author"`
+	Created time.Time    `json:"created"`
+	Items   []ChangeItem `json:"items"`
+}
+
+// ChangeItem is a change to one field of an issue.
+type ChangeItem struct {
+	// Field is the name of the changed field,
+	// such as "status", "assignee" or "Story Points".
+	Field string `json:"field"`
+	// FieldType is "jira" for built-in fields and "custom" for custom fields.
+	FieldType string `json:"fieldtype"`
+	// From and To hold the IDs of the old and new values, if any,
+	// such as the name of an assigned user.
+	From string `json:"from"`
+	To   string `json:"to"`
+	// FromString and ToString hold the text of the old and new values.
+	FromString string `json:"fromString"`
+	ToString   string `json:"toString"`
+}
+
+func (c *Change) UnmarshalJSON(b []byte) error {
+	type alias Change
+	aux := &struct {
+		Created string `json:"created"`
+		*alias
+	}{
+		alias: (*alias)(c),
+	}
+	if err := json.Unmarshal(b, aux); err != nil {
+		return err
+	}
+	var err error
+	c.Created, err = time.Parse(timestamp, aux.Created)
+	if err != nil {
+		return fmt.Errorf("parse created time: %w", err)
+	}
+	return nil
+}
+
+// Changelog returns the changes made to the named issue, oldest first.
+func (c *Client) Changelog(key string) ([]Change, error) {
+	return c.ChangelogContext(context.Background(), key)
+}
+
+// ChangelogContext is like Changelog, with requests made using ctx.
+func (c *Client) ChangelogContext(ctx context.Context, key string) ([]Change, error) {
+	u := *c.APIRoot
+	u.Path = path.Join(u.Path, "issue", key)
+	u.RawQuery = url.Values{"fields": {"updated"}, "expand": {"changelog"}}.Encode()
+	b, err := c.getJSON(ctx, u.String())
+	if err != nil {
+		return nil, err
+	}
+	var v struct {
+		Changelog struct {
+			Total     int
+			Histories []Change
+		}
+	}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return nil, fmt.Errorf("decode changelog: %w", err)
+	}
+	changes := v.Changelog.Histories
+	// Jira Cloud expands only some of the changelog;
+	// the whole of it is listed, page by page, by a separate endpoint.
+	if len(changes) < v.Changelog.Total {
+		changes, err = c.changelogPages(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+	}
+	slices.SortStableFunc(changes, func(a, b Change) int {
+		return a.Created.Compare(b.Created)
+	})
+	return changes, nil
+}
+
+// changelogPageSize is the number of changes requested per page
+// by changelogPages.
+const changelogPageSize = 100
+
+// changelogPages returns every change to the named issue
+// from the paginated changelog endpoint.
+func (c *Client) changelogPages(ctx context.Context, key string) ([]Change, error) {
+	var changes []Change
+	for {
+		u := *c.APIRoot
+		u.Path = path.Join(u.Path, "issue", key, "changelog")
+		u.RawQuery = url.Values{
+			"startAt":    {strconv.Itoa(len(changes))},
+			"maxResults": {strconv.Itoa(changelogPageSize)},
+		}.Encode()
+		b, err := c.getJSON(ctx, u.String())
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Total  int
+			IsLast bool
+			Values []Change
+		}
+		if err := json.Unmarshal(b, &page); err != nil {
+			return nil, fmt.Errorf("decode changelog page: %w", err)
+		}
+		changes = append(changes, page.Values...)
+		if page.IsLast || len(page.Values) == 0 || len(changes) >= page.Total {
+			return changes, nil
+		}
+	}
+}
+
+// printHistory returns the changes as a list of events, oldest first,
+// in the style of the issue command's timeline,
+// such as "* Fred Smith assigned Ann Jones (2024-03-01 12:00:00)".
+// Changed fields are followed by their old and new values
+// on lines prefixed "  - " and "  + ".
+func printHistory(changes []Change) string {
+	buf := &strings.Builder{}
+	for _, c := range changes {
+		for _, it := range c.Items {
+			ev := printChange(&c, &it)
+			if ev == "" {
+				continue
+			}
+			if buf.Len() > 0 {
+				fmt.Fprintln(buf)
+			}
+			buf.WriteString(ev)
+		}
+	}
+	return buf.String()
+}
+
+// printChange returns the event for one item of a change,
+// or an empty string if there is nothing to show.
+func printChange(c *Change, it *ChangeItem) string {
+	date := c.Created.Format(time.DateTime)
+	switch strings.ToLower(it.Field) {
+	case "assignee":
+		if it.ToString == "" {
+			return fmt.Sprintf("* %s unassigned %s (%s)\n", c.Author, it.FromString, date)
+		}
+		return fmt.Sprintf("* %s assigned %s (%s)\n", c.Author, it.ToString, date)
+	case "labels":
+		from, to := strings.Fields(it.FromString), strings.Fields(it.ToString)
+		var events []string
+		for _, l := range to {
+			if !slices.Contains(from, l) {
+				events = append(events, "labeled "+l)
+			}
+		}
+		for _, l := range from {
+			if !slices.Contains(to, l) {
+				events = append(events, "unlabeled "+l)
+			}
+		}
+		if len(events) == 0 {
+			return ""
+		}
+		return fmt.Sprintf("* %s %s (%s)\n", c.Author, strings.Join(events, ", "), date)
+	case "summary":
+		return fmt.Sprintf("* %s changed title (%s)\n  - %s\n  + %s\n", c.Author, date, it.FromString, it.ToString)
+	case "description", "environment":
+		// Too long to show usefully here; see the issue itself.
+		return fmt.Sprintf("* %s changed %s (%s)\n", c.Author, it.Field, date)
+	}
+	s := fmt.Sprintf("* %s changed %s (%s)\n", c.Author, it.Field, date)
+	if it.FromString != "" {
+		s += fmt.Sprintf("  - %s\n", it.FromString)
+	}
+	if it.ToString != "" {
+		s += fmt.Sprintf("  + %s\n", it.ToString)
+	}
+	return s
+}
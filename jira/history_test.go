@@ -0,0 +1,61 @@
+package jira_test
+
+import (
+	"fmt"
+	"io/fs"
+	"strings"
+	"testing"
+
+	"olowe.co/issues/jira"
+	"olowe.co/issues/jira/jiratest"
+)
+
+func TestHistory(t *testing.T) {
+	_, client := newServer(t)
+
+	// More changes than the expanded changelog holds,
+	// so the rest must be paged through.
+	const nchanges = jiratest.MaxResults + 5
+	for i := 1; i <= nchanges; i++ {
+		edit := map[string]any{"summary": fmt.Sprintf("Printer on fire %d", i)}
+		if err := client.UpdateIssue("TEST-1", edit); err != nil {
+			t.Fatal(err)
+		}
+	}
+	changes, err := client.Changelog("TEST-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != nchanges {
+		t.Fatalf("got %d changes, want %d", len(changes), nchanges)
+	}
+	first, last := changes[0], changes[len(changes)-1]
+	if first.Author.Name != "fred" || len(first.Items) != 1 || first.Items[0].ToString != "Printer on fire 1" {
+		t.Errorf("unexpected first change %+v", first)
+	}
+	if _, err := client.Changelog("TEST-999"); err == nil {
+		t.Error("no error getting changelog of missing issue")
+	}
+
+	fsys := &jira.FS{Client: client}
+	b, err := fs.ReadFile(fsys, "TEST/1/history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"* Fred Smith <fred@example.com> changed title",
+		"  - Printer on fire\n  + Printer on fire 1\n",
+		fmt.Sprintf("  + Printer on fire %d\n", nchanges),
+	} {
+		if !strings.Contains(string(b), want) {
+			t.Errorf("history missing %q", want)
+		}
+	}
+	info, err := fs.Stat(fsys, "TEST/1/history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != int64(len(b)) || !info.ModTime().Equal(last.Created) {
+		t.Errorf("stat history: got size %d, modified %s; want %d, %s", info.Size(), info.ModTime(), len(b), last.Created)
+	}
+}
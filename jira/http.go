@@ -2,33 +2,67 @@ package jira
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"net/mail"
 	"net/url"
 	"os"
 	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
+// Client makes requests to the Jira REST API rooted at APIRoot.
+// Each method has a variant taking a context, such as IssueContext,
+// which may be used to cancel requests;
+// the other methods use context.Background.
 type Client struct {
 	*http.Client
-	Debug              bool
+	Debug bool
+	// Auth authenticates each request.
+	// If nil, requests are authenticated with Username and Password,
+	// if set, or are otherwise sent unauthenticated.
+	Auth Authenticator
+	// Username and Password authenticate requests
+	// with HTTP basic authentication when Auth is nil.
+	//
+	// Deprecated: Set Auth to a BasicAuth instead.
 	Username, Password string
-	APIRoot            *url.URL
+
+	APIRoot *url.URL
+	// RequestTimeout, if non-zero, limits the time taken by each request,
+	// including reading the response.
+	// Methods taking a context are further limited by its deadline.
+	RequestTimeout time.Duration
+	// Retry, if not nil, controls how failed requests are retried.
+	// If nil, requests are not retried.
+	Retry *RetryPolicy
+
+	mu        sync.Mutex
+	rateLimit RateLimit
 }
 
 func (c *Client) Projects() ([]Project, error) {
+	return c.ProjectsContext(context.Background())
+}
+
+// ProjectsContext is like Projects, with requests made using ctx.
+func (c *Client) ProjectsContext(ctx context.Context) ([]Project, error) {
 	u := *c.APIRoot
 	u.Path = path.Join(u.Path, "project")
-	resp, err := c.get(u.String())
+	resp, err := c.get(ctx, u.String())
 	if err != nil {
 		return nil, err
 	}
+	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("non-ok status: %s", resp.Status)
+		return nil, responseError(resp)
 	}
-	defer resp.Body.Close()
 	var p []Project
 	if err := json.NewDecoder(resp.Body).Decode(&p); err != nil {
 		return nil, fmt.Errorf("decode project: %w", err)
@@ -37,15 +71,20 @@ func (c *Client) Projects() ([]Project, error) {
 }
 
 func (c *Client) Project(name string) (*Project, error) {
+	return c.ProjectContext(context.Background(), name)
+}
+
+// ProjectContext is like Project, with requests made using ctx.
+func (c *Client) ProjectContext(ctx context.Context, name string) (*Project, error) {
 	u := fmt.Sprintf("%s/project/%s", c.APIRoot, name)
-	resp, err := c.get(u)
+	resp, err := c.get(ctx, u)
 	if err != nil {
 		return nil, err
 	}
+	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("non-ok status: %s", resp.Status)
+		return nil, responseError(resp)
 	}
-	defer resp.Body.Close()
 	var p Project
 	if err := json.NewDecoder(resp.Body).Decode(&p); err != nil {
 		return nil, fmt.Errorf("decode project: %w", err)
@@ -54,39 +93,141 @@ func (c *Client) Project(name string) (*Project, error) {
 }
 
 func (c *Client) Issues(project string) ([]Issue, error) {
+	return c.IssuesContext(context.Background(), project)
+}
+
+// IssuesContext is like Issues, with requests made using ctx.
+func (c *Client) IssuesContext(ctx context.Context, project string) ([]Issue, error) {
 	q := fmt.Sprintf("project = %q", project)
-	return c.SearchIssues(q)
+	return c.SearchIssuesContext(ctx, q)
 }
 
+// SearchIssues returns every issue matching the JQL query,
+// following pagination until all results have been read.
+// To avoid holding large result sets in memory, use Search instead.
 func (c *Client) SearchIssues(query string) ([]Issue, error) {
+	return c.SearchIssuesContext(context.Background(), query)
+}
+
+// SearchIssuesContext is like SearchIssues, with requests made using ctx.
+func (c *Client) SearchIssuesContext(ctx context.Context, query string) ([]Issue, error) {
+	var issues []Issue
+	iter := c.SearchContext(ctx, query)
+	for iter.Next() {
+		issues = append(issues, iter.Page()...)
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+	return issues, nil
+}
+
+// searchPageSize is the number of issues requested per search page.
+// Servers may return fewer.
+const searchPageSize = 100
+
+// SearchIter iterates over pages of issues matching a JQL query.
+// Pages are requested from the server one at a time as Next is called.
+// Use Client.Search to create one.
+type SearchIter struct {
+	ctx     context.Context
+	client  *Client
+	query   string
+	startAt int
+	total   int
+	page    []Issue
+	done    bool
+	err     error
+}
+
+// Search returns an iterator over the issues matching the JQL query.
+// No requests are made until Next is called.
+func (c *Client) Search(query string) *SearchIter {
+	return c.SearchContext(context.Background(), query)
+}
+
+// SearchContext is like Search, with requests made using ctx.
+func (c *Client) SearchContext(ctx context.Context, query string) *SearchIter {
+	return &SearchIter{ctx: ctx, client: c, query: query}
+}
+
+// Next requests the next page of issues, reporting whether one was read.
+// It returns false when the results are exhausted or an error occurred;
+// call Err to distinguish the two.
+func (it *SearchIter) Next() bool {
+	if it.done || it.err != nil {
+		return false
+	}
+	page, err := it.client.searchPage(it.ctx, it.query, it.startAt, searchPageSize)
+	if err != nil {
+		it.err = err
+		return false
+	}
+	it.page = page.Issues
+	it.total = page.Total
+	it.startAt = page.StartAt + len(page.Issues)
+	if len(page.Issues) == 0 {
+		it.done = true
+		return false
+	}
+	if it.startAt >= page.Total {
+		it.done = true
+	}
+	return true
+}
+
+// Page returns the issues read by the most recent call to Next.
+func (it *SearchIter) Page() []Issue { return it.page }
+
+// Total returns the total number of issues matching the query
+// as last reported by the server.
+func (it *SearchIter) Total() int { return it.total }
+
+// Err returns the first error encountered during iteration, if any.
+func (it *SearchIter) Err() error { return it.err }
+
+type searchResult struct {
+	StartAt    int
+	MaxResults int
+	Total      int
+	Issues     []Issue
+}
+
+func (c *Client) searchPage(ctx context.Context, query string, startAt, maxResults int) (*searchResult, error) {
 	u := *c.APIRoot
 	u.Path = path.Join(u.Path, "search")
 	q := make(url.Values)
 	q.Add("jql", query)
+	q.Add("startAt", strconv.Itoa(startAt))
+	q.Add("maxResults", strconv.Itoa(maxResults))
 	u.RawQuery = q.Encode()
-	resp, err := c.get(u.String())
+	resp, err := c.get(ctx, u.String())
 	if err != nil {
 		return nil, err
 	}
+	defer resp.Body.Close()
 	if resp.StatusCode == http.StatusBadRequest {
-		return nil, fmt.Errorf("bad query")
+		return nil, fmt.Errorf("bad query: %w", responseError(resp))
 	}
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("non-ok status: %s", resp.Status)
+		return nil, responseError(resp)
 	}
-	t := struct {
-		Issues []Issue
-	}{}
-	if err := json.NewDecoder(resp.Body).Decode(&t); err != nil {
+	var res searchResult
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
 		return nil, fmt.Errorf("decode issues: %w", err)
 	}
-	return t.Issues, nil
+	return &res, nil
 }
 
 func (c *Client) CheckIssue(name string) (bool, error) {
+	return c.CheckIssueContext(context.Background(), name)
+}
+
+// CheckIssueContext is like CheckIssue, with requests made using ctx.
+func (c *Client) CheckIssueContext(ctx context.Context, name string) (bool, error) {
 	u := *c.APIRoot
 	u.Path = path.Join(u.Path, "issue", name)
-	req, err := http.NewRequest(http.MethodHead, u.String(), nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, u.String(), nil)
 	if err != nil {
 		return false, err
 	}
@@ -94,34 +235,187 @@ func (c *Client) CheckIssue(name string) (bool, error) {
 	if err != nil {
 		return false, err
 	}
-	if resp.StatusCode == http.StatusOK {
+	resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
 		return true, nil
+	case http.StatusNotFound:
+		return false, nil
 	}
-	return false, nil
+	return false, responseError(resp)
 }
 
 func (c *Client) Issue(name string) (*Issue, error) {
+	return c.IssueContext(context.Background(), name)
+}
+
+// IssueContext is like Issue, with requests made using ctx.
+func (c *Client) IssueContext(ctx context.Context, name string) (*Issue, error) {
+	b, err := c.issueJSON(ctx, name, "")
+	if err != nil {
+		return nil, err
+	}
+	var is Issue
+	if err := json.Unmarshal(b, &is); err != nil {
+		return nil, fmt.Errorf("decode issue: %w", err)
+	}
+	return &is, nil
+}
+
+// issueJSON returns the JSON representation of the named issue.
+// If fields is not empty, only those comma-separated fields are returned.
+func (c *Client) issueJSON(ctx context.Context, name, fields string) ([]byte, error) {
 	u := *c.APIRoot
 	u.Path = path.Join(u.Path, "issue", name)
-	resp, err := c.get(u.String())
+	if fields != "" {
+		u.RawQuery = url.Values{"fields": {fields}}.Encode()
+	}
+	return c.getJSON(ctx, u.String())
+}
+
+// getJSON returns the body of a successful GET request for url.
+func (c *Client) getJSON(ctx context.Context, url string) ([]byte, error) {
+	resp, err := c.get(ctx, url)
 	if err != nil {
 		return nil, err
 	}
+	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("non-ok status: %s", resp.Status)
+		return nil, responseError(resp)
+	}
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+	return b, nil
+}
+
+// issueUpdated returns the time the named issue was last updated,
+// requesting only that field.
+func (c *Client) issueUpdated(ctx context.Context, name string) (time.Time, error) {
+	b, err := c.issueJSON(ctx, name, "updated")
+	if err != nil {
+		return time.Time{}, err
 	}
-	defer resp.Body.Close()
 	var is Issue
-	if err := json.NewDecoder(resp.Body).Decode(&is); err != nil {
-		return nil, fmt.Errorf("decode issue: %w", err)
+	if err := json.Unmarshal(b, &is); err != nil {
+		return time.Time{}, fmt.Errorf("decode issue: %w", err)
 	}
-	return &is, nil
+	return is.Updated, nil
+}
+
+// UpdateIssue sets the fields of the named issue.
+// Keys of fields are Jira field IDs, such as "summary" or "assignee";
+// values are encoded as JSON.
+func (c *Client) UpdateIssue(key string, fields map[string]any) error {
+	return c.UpdateIssueContext(context.Background(), key, fields)
+}
+
+// UpdateIssueContext is like UpdateIssue, with requests made using ctx.
+func (c *Client) UpdateIssueContext(ctx context.Context, key string, fields map[string]any) error {
+	body, err := json.Marshal(map[string]any{"fields": fields})
+	if err != nil {
+		return fmt.Errorf("to json: %w", err)
+	}
+	u := *c.APIRoot
+	u.Path = path.Join(u.Path, "issue", key)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Add("Content-Type", "application/json")
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return responseError(resp)
+	}
+	return nil
+}
+
+// Transitions returns the workflow transitions currently available
+// for the named issue.
+func (c *Client) Transitions(key string) ([]Transition, error) {
+	return c.TransitionsContext(context.Background(), key)
+}
+
+// TransitionsContext is like Transitions, with requests made using ctx.
+func (c *Client) TransitionsContext(ctx context.Context, key string) ([]Transition, error) {
+	u := *c.APIRoot
+	u.Path = path.Join(u.Path, "issue", key, "transitions")
+	resp, err := c.get(ctx, u.String())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError(resp)
+	}
+	var t struct {
+		Transitions []Transition
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&t); err != nil {
+		return nil, fmt.Errorf("decode transitions: %w", err)
+	}
+	return t.Transitions, nil
+}
+
+// Transition moves the named issue through its workflow.
+// The transition is found by name, or by the name of the status
+// it leads to, ignoring case.
+func (c *Client) Transition(key, name string) error {
+	return c.TransitionContext(context.Background(), key, name)
+}
+
+// TransitionContext is like Transition, with requests made using ctx.
+func (c *Client) TransitionContext(ctx context.Context, key, name string) error {
+	transitions, err := c.TransitionsContext(ctx, key)
+	if err != nil {
+		return fmt.Errorf("get transitions: %w", err)
+	}
+	var id string
+	for _, t := range transitions {
+		if strings.EqualFold(t.Name, name) || strings.EqualFold(t.To.Name, name) {
+			id = t.ID
+			break
+		}
+	}
+	if id == "" {
+		names := make([]string, len(transitions))
+		for i := range transitions {
+			names[i] = transitions[i].Name
+		}
+		return fmt.Errorf("no transition %q: available transitions are %s", name, strings.Join(names, ", "))
+	}
+
+	body, err := json.Marshal(map[string]any{"transition": map[string]string{"id": id}})
+	if err != nil {
+		return fmt.Errorf("to json: %w", err)
+	}
+	u := *c.APIRoot
+	u.Path = path.Join(u.Path, "issue", key, "transitions")
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Add("Content-Type", "application/json")
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return responseError(resp)
+	}
+	return nil
 }
 
-func (c *Client) checkComment(ikey, id string) (bool, error) {
+func (c *Client) checkComment(ctx context.Context, ikey, id string) (bool, error) {
 	u := *c.APIRoot
 	u.Path = path.Join(u.Path, "issue", ikey, "comment", id)
-	req, err := http.NewRequest(http.MethodHead, u.String(), nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, u.String(), nil)
 	if err != nil {
 		return false, err
 	}
@@ -129,20 +423,32 @@ func (c *Client) checkComment(ikey, id string) (bool, error) {
 	if err != nil {
 		return false, err
 	}
-	if resp.StatusCode == http.StatusOK {
+	resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
 		return true, nil
+	case http.StatusNotFound:
+		return false, nil
 	}
-	return false, nil
+	return false, responseError(resp)
 }
 
 func (c *Client) Comment(ikey, id string) (*Comment, error) {
+	return c.CommentContext(context.Background(), ikey, id)
+}
+
+// CommentContext is like Comment, with requests made using ctx.
+func (c *Client) CommentContext(ctx context.Context, ikey, id string) (*Comment, error) {
 	u := *c.APIRoot
 	u.Path = path.Join(u.Path, "issue", ikey, "comment", id)
-	resp, err := c.get(u.String())
+	resp, err := c.get(ctx, u.String())
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError(resp)
+	}
 	var com Comment
 	if err := json.NewDecoder(resp.Body).Decode(&com); err != nil {
 		return nil, fmt.Errorf("decode comment: %w", err)
@@ -151,6 +457,11 @@ func (c *Client) Comment(ikey, id string) (*Comment, error) {
 }
 
 func (c *Client) PostComment(issueKey string, body io.Reader) error {
+	return c.PostCommentContext(context.Background(), issueKey, body)
+}
+
+// PostCommentContext is like PostComment, with requests made using ctx.
+func (c *Client) PostCommentContext(ctx context.Context, issueKey string, body io.Reader) error {
 	b, err := io.ReadAll(body)
 	if err != nil {
 		return fmt.Errorf("read body: %w", err)
@@ -161,7 +472,7 @@ func (c *Client) PostComment(issueKey string, body io.Reader) error {
 		return fmt.Errorf("to json: %w", err)
 	}
 	u := fmt.Sprintf("%s/issue/%s/comment", c.APIRoot, issueKey)
-	req, err := http.NewRequest(http.MethodPost, u, bytes.NewReader(hbody))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(hbody))
 	if err != nil {
 		return err
 	}
@@ -170,32 +481,197 @@ func (c *Client) PostComment(issueKey string, body io.Reader) error {
 	if err != nil {
 		return err
 	}
+	defer resp.Body.Close()
 	if resp.StatusCode >= http.StatusBadRequest {
-		return fmt.Errorf("non-ok status: %s", resp.Status)
+		return responseError(resp)
 	}
 	return nil
+}
 
+// UpdateComment replaces the body of the comment id on the named issue.
+func (c *Client) UpdateComment(issueKey, id string, body io.Reader) error {
+	return c.UpdateCommentContext(context.Background(), issueKey, id, body)
 }
 
-func Create(APIRoot string, issue Issue) (*Issue, error) {
-	b, err := json.Marshal(&issue)
+// UpdateCommentContext is like UpdateComment, with requests made using ctx.
+func (c *Client) UpdateCommentContext(ctx context.Context, issueKey, id string, body io.Reader) error {
+	b, err := io.ReadAll(body)
 	if err != nil {
-		return nil, fmt.Errorf("to json: %w", err)
+		return fmt.Errorf("read body: %w", err)
 	}
-	u := APIRoot + "/issue"
-	resp, err := http.Post(u, "application/json", bytes.NewReader(b))
+	cm := Comment{Body: string(b)}
+	hbody, err := json.Marshal(&cm)
+	if err != nil {
+		return fmt.Errorf("to json: %w", err)
+	}
+	u := *c.APIRoot
+	u.Path = path.Join(u.Path, "issue", issueKey, "comment", id)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u.String(), bytes.NewReader(hbody))
+	if err != nil {
+		return err
+	}
+	req.Header.Add("Content-Type", "application/json")
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return responseError(resp)
+	}
+	return nil
+}
+
+// DeleteComment deletes the comment id from the named issue.
+func (c *Client) DeleteComment(issueKey, id string) error {
+	return c.DeleteCommentContext(context.Background(), issueKey, id)
+}
+
+// DeleteCommentContext is like DeleteComment, with requests made using ctx.
+func (c *Client) DeleteCommentContext(ctx context.Context, issueKey, id string) error {
+	u := *c.APIRoot
+	u.Path = path.Join(u.Path, "issue", issueKey, "comment", id)
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return responseError(resp)
+	}
+	return nil
+}
+
+// Myself returns the user authenticated by the client.
+func (c *Client) Myself() (*User, error) {
+	return c.MyselfContext(context.Background())
+}
+
+// MyselfContext is like Myself, with requests made using ctx.
+func (c *Client) MyselfContext(ctx context.Context) (*User, error) {
+	u := *c.APIRoot
+	u.Path = path.Join(u.Path, "myself")
+	resp, err := c.get(ctx, u.String())
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("non-ok status %s", resp.Status)
+		return nil, responseError(resp)
+	}
+	var me User
+	if err := json.NewDecoder(resp.Body).Decode(&me); err != nil {
+		return nil, fmt.Errorf("decode user: %w", err)
+	}
+	return &me, nil
+}
+
+// LookupUser returns the user named by s, which is either a username
+// or a mail address, optionally with a display name,
+// as printed by User.String.
+// Users named by address are searched for;
+// it is an error if no user or more than one user has the address.
+// A username is returned as is, without checking that the user exists.
+func (c *Client) LookupUser(s string) (*User, error) {
+	return c.LookupUserContext(context.Background(), s)
+}
+
+// LookupUserContext is like LookupUser, with requests made using ctx.
+func (c *Client) LookupUserContext(ctx context.Context, s string) (*User, error) {
+	s = strings.TrimSpace(s)
+	addr, err := mail.ParseAddress(s)
+	if err != nil {
+		return &User{Name: s}, nil
+	}
+	u := *c.APIRoot
+	u.Path = path.Join(u.Path, "user", "search")
+	// Jira Server and Data Center search by the username parameter,
+	// Jira Cloud by query.
+	u.RawQuery = url.Values{"username": {addr.Address}, "query": {addr.Address}}.Encode()
+	b, err := c.getJSON(ctx, u.String())
+	if err != nil {
+		return nil, fmt.Errorf("search for user %s: %w", addr.Address, err)
+	}
+	var found []User
+	if err := json.Unmarshal(b, &found); err != nil {
+		return nil, fmt.Errorf("decode users: %w", err)
+	}
+	var matched []User
+	for _, user := range found {
+		if strings.EqualFold(user.Email, addr.Address) {
+			matched = append(matched, user)
+		}
+	}
+	// Jira Cloud hides the addresses of most users,
+	// so trust a lone result.
+	if len(matched) == 0 && len(found) == 1 && found[0].Email == "" {
+		matched = found
+	}
+	switch len(matched) {
+	case 0:
+		return nil, fmt.Errorf("no user with address %s", addr.Address)
+	case 1:
+		return &matched[0], nil
+	}
+	return nil, fmt.Errorf("%d users with address %s", len(matched), addr.Address)
+}
+
+// CreateIssue creates a new issue from the project key, type name, summary,
+// description, assignee and labels of issue.
+// The assignee is identified by its account ID or, failing that, its username;
+// see LookupUser to find a user by mail address.
+// Other fields of issue are ignored.
+// The returned issue holds only the ID, key and URL of the created issue.
+func (c *Client) CreateIssue(issue *Issue) (*Issue, error) {
+	return c.CreateIssueContext(context.Background(), issue)
+}
+
+// CreateIssueContext is like CreateIssue, with requests made using ctx.
+func (c *Client) CreateIssueContext(ctx context.Context, issue *Issue) (*Issue, error) {
+	fields := map[string]any{
+		"project": map[string]string{"key": issue.Project.Key},
+		"summary": issue.Summary,
+	}
+	if issue.Type.Name != "" {
+		fields["issuetype"] = map[string]string{"name": issue.Type.Name}
+	}
+	if issue.Description != "" {
+		fields["description"] = issue.Description
+	}
+	if issue.Assignee.Name != "" || issue.Assignee.AccountID != "" {
+		fields["assignee"] = issue.Assignee.ref()
+	}
+	if len(issue.Labels) > 0 {
+		fields["labels"] = issue.Labels
+	}
+	body, err := json.Marshal(map[string]any{"fields": fields})
+	if err != nil {
+		return nil, fmt.Errorf("to json: %w", err)
+	}
+	u := *c.APIRoot
+	u.Path = path.Join(u.Path, "issue")
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("Content-Type", "application/json")
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
 	}
-	var i Issue
-	if err := json.NewDecoder(resp.Body).Decode(&i); err != nil {
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil, responseError(resp)
+	}
+	var created Issue
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
 		return nil, fmt.Errorf("decode created issue: %w", err)
 	}
-	return &i, nil
+	return &created, nil
 }
 
 func CreateComment(APIRoot, issueKey string, body io.Reader) error {
@@ -213,14 +689,15 @@ func CreateComment(APIRoot, issueKey string, body io.Reader) error {
 	if err != nil {
 		return err
 	}
+	defer resp.Body.Close()
 	if resp.StatusCode >= http.StatusBadRequest {
-		return fmt.Errorf("non-ok status: %s", resp.Status)
+		return responseError(resp)
 	}
 	return nil
 }
 
-func (c *Client) get(url string) (*http.Response, error) {
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+func (c *Client) get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -231,11 +708,88 @@ func (c *Client) do(req *http.Request) (*http.Response, error) {
 	if c.Client == nil {
 		c.Client = http.DefaultClient
 	}
-	if c.Username != "" && c.Password != "" {
+	for n := 0; ; n++ {
+		resp, err := c.send(req)
+		if resp != nil {
+			c.setRateLimit(parseRateLimit(resp, time.Now()))
+		}
+		if c.Retry == nil {
+			return resp, err
+		}
+		wait, ok := c.Retry.retryDelay(req, resp, err, n)
+		if !ok {
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, maxErrorBody))
+			resp.Body.Close()
+		}
+		if c.Debug {
+			fmt.Fprintf(os.Stderr, "retry %s %s in %s\n", req.Method, req.URL, wait)
+		}
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(wait):
+		}
+		if req.Body != nil && req.Body != http.NoBody {
+			if req.GetBody == nil {
+				return nil, fmt.Errorf("retry %s %s: cannot resend request body", req.Method, req.URL)
+			}
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, fmt.Errorf("retry %s %s: %w", req.Method, req.URL, err)
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+	}
+}
+
+// send makes a single attempt at the request.
+// Requests are authenticated only if sent to the host of APIRoot,
+// so credentials are not given away to other hosts named by Jira,
+// such as in the content URL of an attachment.
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	switch {
+	case !sameOrigin(req.URL, c.APIRoot):
+	case c.Auth != nil:
+		if err := c.Auth.Authenticate(req); err != nil {
+			return nil, fmt.Errorf("authenticate: %w", err)
+		}
+	case c.Username != "" && c.Password != "":
 		req.SetBasicAuth(c.Username, c.Password)
 	}
 	if c.Debug {
 		fmt.Fprintln(os.Stderr, req.Method, req.URL)
 	}
-	return c.Do(req)
+	if c.RequestTimeout <= 0 {
+		return c.Do(req)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), c.RequestTimeout)
+	resp, err := c.Do(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	// The timeout applies until the body is read and closed.
+	resp.Body = &cancelBody{resp.Body, cancel}
+	return resp, nil
+}
+
+// sameOrigin reports whether u has the same scheme and host as root.
+func sameOrigin(u, root *url.URL) bool {
+	return root != nil && strings.EqualFold(u.Scheme, root.Scheme) && strings.EqualFold(u.Host, root.Host)
+}
+
+// cancelBody calls cancel when the response body is closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
 }
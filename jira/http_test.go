@@ -1,31 +1,64 @@
-package jira
+package jira_test
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"io"
+	"io/fs"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
-	"path"
+	"strings"
 	"testing"
 	"testing/fstest"
+	"time"
+
+	"olowe.co/issues/jira"
+	"olowe.co/issues/jira/jiratest"
 )
 
-func handleComment(w http.ResponseWriter, req *http.Request) {
-	id := path.Base(req.URL.Path)
-	http.ServeFile(w, req, "testdata/comment/"+id)
+var fred = jira.User{Name: "fred", DisplayName: "Fred Smith", Email: "fred@example.com"}
+
+// newServer returns a test server holding the project TEST
+// and its issue TEST-1, with one comment by fred,
+// and a client authenticated as fred.
+func newServer(t *testing.T) (*jiratest.Server, *jira.Client) {
+	t.Helper()
+	srv := jiratest.NewServer()
+	t.Cleanup(srv.Close)
+	srv.AddUser(fred, "secret")
+	srv.AddProject("TEST", "Test")
+	key := srv.AddIssue("TEST", map[string]any{
+		"summary":     "Printer on fire",
+		"description": "It is very hot.",
+		"reporter":    map[string]string{"name": "fred"},
+		"assignee":    map[string]string{"name": "fred"},
+	})
+	srv.AddComment(key, fred, "Put it out!")
+	client := srv.Client()
+	client.Auth = &jira.BasicAuth{Username: "fred", Password: "secret"}
+	return srv, client
 }
 
-func TestGet(t *testing.T) {
-	srv := newFakeServer("testdata")
-	defer srv.Close()
-	u, err := url.Parse(srv.URL)
+// commentID returns the ID of the first comment on the issue key.
+func commentID(t *testing.T, client *jira.Client, key string) string {
+	t.Helper()
+	is, err := client.Issue(key)
 	if err != nil {
 		t.Fatal(err)
 	}
-	client := &Client{APIRoot: u}
+	if len(is.Comments) == 0 {
+		t.Fatalf("%s has no comments", key)
+	}
+	return is.Comments[0].ID
+}
+
+func TestGet(t *testing.T) {
+	_, client := newServer(t)
 
 	project := "TEST"
 	issue := "TEST-1"
-	comment := "69"
 	if _, err := client.Project(project); err != nil {
 		t.Fatalf("get project %s: %v", project, err)
 	}
@@ -35,6 +68,7 @@ func TestGet(t *testing.T) {
 	if _, err := client.Issue(issue); err != nil {
 		t.Fatalf("get issue %s: %v", issue, err)
 	}
+	comment := commentID(t, client, issue)
 	c, err := client.Comment(issue, comment)
 	if err != nil {
 		t.Fatalf("get comment %s from %s: %v", comment, issue, err)
@@ -43,8 +77,8 @@ func TestGet(t *testing.T) {
 		t.Fatalf("wanted comment id %s, got %s", comment, c.ID)
 	}
 
-	fsys := &FS{Client: client}
-	f, err := fsys.Open("TEST/1/69")
+	fsys := &jira.FS{Client: client}
+	f, err := fsys.Open("TEST/1/" + comment)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -60,9 +94,260 @@ func TestGet(t *testing.T) {
 		"TEST",
 		"TEST/1",
 		"TEST/1/issue",
-		"TEST/1/69",
+		"TEST/1/thread",
+		"TEST/1/" + comment,
 	}
 	if err := fstest.TestFS(fsys, expected...); err != nil {
 		t.Error(err)
 	}
 }
+
+func TestSearchPagination(t *testing.T) {
+	srv := jiratest.NewServer()
+	defer srv.Close()
+	const nissues = 2*jiratest.MaxResults + 20
+	for i := 1; i <= nissues; i++ {
+		srv.AddIssue("TEST", map[string]any{"summary": fmt.Sprintf("issue %d", i)})
+	}
+	client := srv.Client()
+
+	issues, err := client.SearchIssues("project = TEST")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != nissues {
+		t.Errorf("got %d issues, want %d", len(issues), nissues)
+	}
+	seen := make(map[string]bool)
+	for _, is := range issues {
+		if seen[is.Key] {
+			t.Errorf("duplicate issue %s in results", is.Key)
+		}
+		seen[is.Key] = true
+	}
+
+	var pages, n int
+	iter := client.Search("project = TEST")
+	for iter.Next() {
+		pages++
+		n += len(iter.Page())
+	}
+	if err := iter.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if pages != 3 {
+		t.Errorf("iterated over %d pages, want %d", pages, 3)
+	}
+	if n != nissues || iter.Total() != nissues {
+		t.Errorf("iterated over %d of %d issues, want %d", n, iter.Total(), nissues)
+	}
+}
+
+func TestTransition(t *testing.T) {
+	_, client := newServer(t)
+
+	// transitions may be named by either the transition or its target status.
+	for _, name := range []string{"start progress", "Done"} {
+		if err := client.Transition("TEST-1", name); err != nil {
+			t.Fatalf("transition %q: %v", name, err)
+		}
+	}
+	issue, err := client.Issue("TEST-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issue.Status.Name != "Done" {
+		t.Errorf("status = %q, want %q", issue.Status.Name, "Done")
+	}
+	if err := client.Transition("TEST-1", "Nonexistent"); err == nil {
+		t.Error("nil error for nonexistent transition")
+	}
+}
+
+func TestCreateIssue(t *testing.T) {
+	_, client := newServer(t)
+
+	issue := &jira.Issue{
+		Project:     jira.Project{Key: "TEST"},
+		Type:        jira.IssueType{Name: "Task"},
+		Summary:     "Something is broken",
+		Description: "It doesn't work.",
+		Assignee:    jira.User{Name: "fred"},
+		Labels:      []string{"bug", "urgent"},
+	}
+	created, err := client.CreateIssue(issue)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.Key != "TEST-2" {
+		t.Errorf("created issue key = %q, want %q", created.Key, "TEST-2")
+	}
+	got, err := client.Issue(created.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Summary != issue.Summary || got.Description != issue.Description {
+		t.Errorf("created issue %q with description %q, want %q with %q", got.Summary, got.Description, issue.Summary, issue.Description)
+	}
+	if got.Assignee.Name != issue.Assignee.Name || got.Type.Name != issue.Type.Name {
+		t.Errorf("created issue assigned to %q with type %q, want %q and %q", got.Assignee.Name, got.Type.Name, issue.Assignee.Name, issue.Type.Name)
+	}
+	if strings.Join(got.Labels, " ") != strings.Join(issue.Labels, " ") {
+		t.Errorf("created issue labels = %q, want %q", got.Labels, issue.Labels)
+	}
+}
+
+func TestEditComment(t *testing.T) {
+	_, client := newServer(t)
+	id := commentID(t, client, "TEST-1")
+
+	want := "I take it all back."
+	if err := client.UpdateComment("TEST-1", id, strings.NewReader(want)); err != nil {
+		t.Fatal(err)
+	}
+	c, err := client.Comment("TEST-1", id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Body != want {
+		t.Errorf("comment body = %q, want %q", c.Body, want)
+	}
+
+	if err := client.DeleteComment("TEST-1", id); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Comment("TEST-1", id); err == nil {
+		t.Error("nil error getting deleted comment")
+	}
+}
+
+func TestErrors(t *testing.T) {
+	srv, client := newServer(t)
+
+	_, err := client.Comment("TEST-1", "999")
+	if !errors.Is(err, jira.ErrNotFound) {
+		t.Errorf("want ErrNotFound getting missing comment, got %v", err)
+	}
+	if errors.Is(err, jira.ErrUnauthorized) || errors.Is(err, jira.ErrRateLimited) {
+		t.Errorf("%v matches unrelated sentinel errors", err)
+	}
+
+	_, err = client.CreateIssue(&jira.Issue{Project: jira.Project{Key: "TEST"}})
+	var jerr *jira.Error
+	if !errors.As(err, &jerr) {
+		t.Fatalf("want *Error creating issue without summary, got %T %v", err, err)
+	}
+	if jerr.StatusCode != http.StatusBadRequest || jerr.Method != http.MethodPost {
+		t.Errorf("got status %d for method %s, want %d for %s", jerr.StatusCode, jerr.Method, http.StatusBadRequest, http.MethodPost)
+	}
+	if jerr.Fields["summary"] == "" {
+		t.Errorf("missing summary field error in %v", jerr)
+	}
+
+	fsys := &jira.FS{Client: client}
+	f, err := fsys.Open("TEST/1/issue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	srv.DeleteIssue("TEST-1")
+	if _, err := f.Stat(); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("want fs.ErrNotExist for deleted issue, got %v", err)
+	}
+}
+
+func TestLookupUser(t *testing.T) {
+	srv, client := newServer(t)
+	srv.AddUser(jira.User{Name: "fredrik", DisplayName: "Fredrik", Email: "fredrik@example.com"}, "secret")
+	for _, s := range []string{"Fred Smith <fred@example.com>", "FRED@example.com", "fred"} {
+		u, err := client.LookupUser(s)
+		if err != nil {
+			t.Errorf("look up %q: %v", s, err)
+			continue
+		}
+		if u.Name != "fred" {
+			t.Errorf("look up %q: got user %q, want fred", s, u.Name)
+		}
+	}
+	if _, err := client.LookupUser("nobody@example.com"); err == nil {
+		t.Error("no error looking up unknown address")
+	}
+}
+
+func TestSearchError(t *testing.T) {
+	msg := "Field 'colour' does not exist or you do not have permission to view it."
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, `{"errorMessages": [%q], "errors": {}}`, msg)
+	}))
+	defer srv.Close()
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &jira.Client{APIRoot: u}
+	_, err = client.SearchIssues("colour = blue")
+	var jerr *jira.Error
+	if !errors.As(err, &jerr) {
+		t.Fatalf("want *Error, got %T %v", err, err)
+	}
+	if len(jerr.Messages) != 1 || jerr.Messages[0] != msg {
+		t.Errorf("error messages = %q, want %q", jerr.Messages, msg)
+	}
+	if !strings.Contains(err.Error(), msg) {
+		t.Errorf("error %q does not contain reason %q", err, msg)
+	}
+}
+
+func TestContext(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		// hang until the client gives up.
+		<-req.Context().Done()
+	}))
+	defer srv.Close()
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := &jira.Client{APIRoot: u, RequestTimeout: 50 * time.Millisecond}
+	if _, err := client.Issue("TEST-1"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("want %v from hung request, got %v", context.DeadlineExceeded, err)
+	}
+
+	client = &jira.Client{APIRoot: u}
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	if _, err := client.IssueContext(ctx, "TEST-1"); !errors.Is(err, context.Canceled) {
+		t.Errorf("want %v from cancelled request, got %v", context.Canceled, err)
+	}
+
+	fsys := (&jira.FS{Client: client}).WithContext(ctx)
+	if _, err := fsys.Open("TEST/1/issue"); !errors.Is(err, context.Canceled) {
+		t.Errorf("want %v opening file with cancelled context, got %v", context.Canceled, err)
+	}
+}
+
+func TestRawCopy(t *testing.T) {
+	srv, client := newServer(t)
+	key := srv.AddIssue("TEST", map[string]any{"summary": "Formatted", "description": "h1. Fire\nIt is *very* hot."})
+	name := strings.Replace(key, "-", "/", 1) + "/issue"
+	fsys := &jira.FS{Client: client}
+	plain, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := *fsys
+	raw.Raw = true
+	b, err := fs.ReadFile(&raw, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), "h1. Fire") {
+		t.Errorf("copy with Raw set shows plain text:\n%s", b)
+	}
+	if strings.Contains(string(plain), "h1. Fire") {
+		t.Errorf("FS without Raw shows Jira text formatting:\n%s", plain)
+	}
+}
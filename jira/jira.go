@@ -9,29 +9,157 @@ import (
 const timestamp = "2006-01-02T15:04:05.999-0700"
 
 type Issue struct {
-	ID       string // TODO(otl): int?
-	URL      string
-	Key      string
-	Reporter User
-	Assignee User
-	Summary  string
-	Status   struct {
-		Name string `json:"name"`
-	} `json:"status"`
+	ID          string // TODO(otl): int?
+	URL         string
+	Key         string
+	Reporter    User
+	Assignee    User
+	Summary     string
+	Status      Status    `json:"status"`
+	Type        IssueType `json:"issuetype"`
+	Priority    Priority  `json:"priority"`
+	Labels      []string
+	Components  []Component
+	FixVersions []Version `json:"fixVersions"`
+	// AffectsVersions are the versions in which the issue was found.
+	AffectsVersions []Version  `json:"versions"`
+	Resolution      Resolution `json:"resolution"`
+	Resolved        time.Time
+	Due             time.Time
+	// Parent is the issue containing this one, such as the issue
+	// of a subtask or the epic of a story.
+	// Only its ID, key, URL, summary, status and type are set.
+	Parent *Issue `json:"parent"`
+	// Watchers is the number of users watching the issue.
+	Watchers    int
 	Description string
 	Project     Project
 	Created     time.Time
 	Updated     time.Time
 	Comments    []Comment
-	Links       []Issue
-	Subtasks    []Issue
+	Links       []Link
+	// Subtasks are the issue's subtasks.
+	// Only their ID, key, URL, summary, status, priority and type are set.
+	Subtasks    []Issue      `json:"subtasks"`
+	Attachments []Attachment `json:"attachment"`
+	// Extra holds the fields not decoded into the other members of Issue,
+	// such as custom fields, keyed by field ID.
+	// Fields with null values are omitted.
+	// See Client.Fields for the names of fields.
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// decodedFields holds the IDs of the fields decoded into an Issue.
+var decodedFields = map[string]bool{
+	"summary":        true,
+	"status":         true,
+	"issuetype":      true,
+	"priority":       true,
+	"labels":         true,
+	"components":     true,
+	"fixVersions":    true,
+	"versions":       true,
+	"resolution":     true,
+	"resolutiondate": true,
+	"duedate":        true,
+	"parent":         true,
+	"watches":        true,
+	"description":    true,
+	"project":        true,
+	"created":        true,
+	"updated":        true,
+	"comment":        true,
+	"issuelinks":     true,
+	"subtasks":       true,
+	"attachment":     true,
+	"reporter":       true,
+	"assignee":       true,
 }
 
 type Project struct {
 	ID string `json:"id"` // TODO(otl): int?
-	// Name string `json:"name"`
-	Key string `json:"key"`
-	URL string `json:"self"`
+	// Title is the full name of the project, such as "Test project".
+	// It is not Name, which reports the key to implement fs.FileInfo.
+	Title string `json:"name"`
+	Key   string `json:"key"`
+	URL   string `json:"self"`
+}
+
+type IssueType struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type Priority struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// Resolution describes how an issue was resolved, such as "Fixed".
+// Unresolved issues have a zero Resolution.
+type Resolution struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type Component struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// Version is a release of a project.
+type Version struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Released bool   `json:"released"`
+}
+
+// Link is a link from an issue to another issue.
+type Link struct {
+	ID   string
+	Type LinkType
+	// Outward reports whether the link is described by the
+	// outward description of its type, such as "blocks",
+	// rather than the inward description, such as "is blocked by".
+	Outward bool
+	// Issue is the linked issue.
+	// Only its ID, key, URL, summary, status, priority and type are set.
+	Issue Issue
+}
+
+// Relation returns the description of the link from the linking issue,
+// such as "blocks" in "TEST-1 blocks TEST-4".
+// If the link type has no description for the link's direction,
+// the name of the type is returned instead.
+func (l *Link) Relation() string {
+	r := l.Type.Inward
+	if l.Outward {
+		r = l.Type.Outward
+	}
+	if r == "" {
+		return l.Type.Name
+	}
+	return r
+}
+
+// LinkType is a kind of link between issues,
+// such as one issue blocking another.
+type LinkType struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`    // such as "Blocks"
+	Inward  string `json:"inward"`  // such as "is blocked by"
+	Outward string `json:"outward"` // such as "blocks"
+}
+
+type Status struct {
+	Name string `json:"name"`
+}
+
+// Transition moves an issue from one status to another in its workflow.
+type Transition struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	To   Status `json:"to"`
 }
 
 type Comment struct {
@@ -69,7 +197,13 @@ func (c *Comment) UnmarshalJSON(b []byte) error {
 }
 
 type User struct {
-	Name        string `json:"name"`
+	// Name is the username; Jira Cloud leaves it empty.
+	Name string `json:"name"`
+	// Key identifies the user on Jira Server and Data Center,
+	// even if renamed.
+	Key string `json:"key,omitempty"`
+	// AccountID identifies the user on Jira Cloud.
+	AccountID   string `json:"accountId,omitempty"`
 	Email       string `json:"emailAddress"`
 	DisplayName string `json:"displayName"`
 }
@@ -77,10 +211,21 @@ type User struct {
 func (u User) String() string {
 	if u.DisplayName == "" {
 		return u.Email
+	} else if u.Email == "" {
+		return u.DisplayName
 	}
 	return fmt.Sprintf("%s <%s>", u.DisplayName, u.Email)
 }
 
+// ref returns the reference to u sent to Jira to set a user field:
+// by account ID on Jira Cloud, otherwise by username.
+func (u *User) ref() map[string]string {
+	if u.AccountID != "" {
+		return map[string]string{"accountId": u.AccountID}
+	}
+	return map[string]string{"name": u.Name}
+}
+
 func (issue *Issue) UnmarshalJSON(b []byte) error {
 	aux := &struct {
 		ID     string
@@ -94,13 +239,24 @@ func (issue *Issue) UnmarshalJSON(b []byte) error {
 	issue.ID = aux.ID
 	issue.URL = aux.Self
 	issue.Key = aux.Key
+	if len(aux.Fields) == 0 {
+		// e.g. the abbreviated issue returned on creation.
+		return nil
+	}
 
 	type alias Issue
 	iaux := &struct {
-		Created    string
-		Updated    string
+		Created        string
+		Updated        string
+		ResolutionDate string
+		DueDate        string
+		Watches        struct {
+			WatchCount int
+		}
 		Comment    map[string]json.RawMessage
 		IssueLinks []struct {
+			ID           string
+			Type         LinkType
 			InwardIssue  *Issue
 			OutwardIssue *Issue
 		}
@@ -125,6 +281,33 @@ func (issue *Issue) UnmarshalJSON(b []byte) error {
 			return fmt.Errorf("updated time: %w", err)
 		}
 	}
+	if iaux.ResolutionDate != "" {
+		issue.Resolved, err = time.Parse(timestamp, iaux.ResolutionDate)
+		if err != nil {
+			return fmt.Errorf("resolution time: %w", err)
+		}
+	}
+	if iaux.DueDate != "" {
+		issue.Due, err = time.Parse(time.DateOnly, iaux.DueDate)
+		if err != nil {
+			return fmt.Errorf("due date: %w", err)
+		}
+	}
+	issue.Watchers = iaux.Watches.WatchCount
+
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(aux.Fields, &all); err != nil {
+		return err
+	}
+	for k, v := range all {
+		if decodedFields[k] || string(v) == "null" {
+			continue
+		}
+		if issue.Extra == nil {
+			issue.Extra = make(map[string]json.RawMessage)
+		}
+		issue.Extra[k] = v
+	}
 	if bb, ok := iaux.Comment["comments"]; ok {
 		if err := json.Unmarshal(bb, &issue.Comments); err != nil {
 			return fmt.Errorf("unmarshal comments: %w", err)
@@ -132,10 +315,10 @@ func (issue *Issue) UnmarshalJSON(b []byte) error {
 	}
 	for _, l := range iaux.IssueLinks {
 		if l.InwardIssue != nil {
-			issue.Links = append(issue.Links, *l.InwardIssue)
+			issue.Links = append(issue.Links, Link{ID: l.ID, Type: l.Type, Issue: *l.InwardIssue})
 		}
 		if l.OutwardIssue != nil {
-			issue.Links = append(issue.Links, *l.OutwardIssue)
+			issue.Links = append(issue.Links, Link{ID: l.ID, Type: l.Type, Outward: true, Issue: *l.OutwardIssue})
 		}
 	}
 	return nil
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"os"
 	"testing"
+	"time"
 )
 
 func TestDecode(t *testing.T) {
@@ -24,17 +25,88 @@ func TestDecode(t *testing.T) {
 	}
 }
 
-/*
-func TestSubtasks(t *testing.T) {
-	f, err := os.Open("testdata/subtasks")
+func TestDecodeFields(t *testing.T) {
+	b, err := os.ReadFile("testdata/issue/TEST-1")
 	if err != nil {
 		t.Fatal(err)
 	}
-	defer f.Close()
 	var is Issue
-	if err := json.NewDecoder(f).Decode(&is); err != nil {
+	if err := json.Unmarshal(b, &is); err != nil {
 		t.Fatal(err)
 	}
-	fmt.Println(is.Subtasks)
+	if is.Resolution.Name != "Fixed" || is.Resolved.IsZero() {
+		t.Errorf("got resolution %q at %s, want Fixed", is.Resolution.Name, is.Resolved)
+	}
+	if len(is.FixVersions) != 1 || is.FixVersions[0].Name != "4.4" || !is.FixVersions[0].Released {
+		t.Errorf("got fix versions %+v, want released 4.4", is.FixVersions)
+	}
+	if len(is.Components) != 1 || is.Components[0].Name != "User Management - Others" {
+		t.Errorf("got components %+v", is.Components)
+	}
+	if is.Watchers != 213 {
+		t.Errorf("got %d watchers, want 213", is.Watchers)
+	}
+	if is.Project.Title != "Jira Data Center" {
+		t.Errorf("got project title %q", is.Project.Title)
+	}
+
+	b = []byte(`{"key": "TEST-2", "fields": {
+		"priority": {"id": "2", "name": "High"},
+		"duedate": "2024-03-01",
+		"versions": [{"id": "1", "name": "1.0"}],
+		"parent": {"id": "10000", "key": "TEST-1", "fields": {"summary": "Epic"}},
+		"resolution": null
+	}}`)
+	is = Issue{}
+	if err := json.Unmarshal(b, &is); err != nil {
+		t.Fatal(err)
+	}
+	if is.Priority.Name != "High" {
+		t.Errorf("got priority %q, want High", is.Priority.Name)
+	}
+	if !is.Due.Equal(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("got due date %s, want 2024-03-01", is.Due)
+	}
+	if len(is.AffectsVersions) != 1 || is.AffectsVersions[0].Name != "1.0" {
+		t.Errorf("got affects versions %+v", is.AffectsVersions)
+	}
+	if is.Parent == nil || is.Parent.Key != "TEST-1" || is.Parent.Summary != "Epic" {
+		t.Errorf("got parent %+v, want TEST-1", is.Parent)
+	}
+	if is.Resolution.Name != "" {
+		t.Errorf("unresolved issue has resolution %q", is.Resolution.Name)
+	}
+}
+
+func TestDecodeLinks(t *testing.T) {
+	b, err := os.ReadFile("testdata/issue/TEST-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var is Issue
+	if err := json.Unmarshal(b, &is); err != nil {
+		t.Fatal(err)
+	}
+	if len(is.Links) != 14 {
+		t.Fatalf("got %d links, want 14", len(is.Links))
+	}
+	l := is.Links[0]
+	if l.ID != "50308" || l.Issue.Key != "JSWSERVER-2855" || l.Relation() != "blocks" {
+		t.Errorf("got link %s %s %s, want 50308 blocks JSWSERVER-2855", l.ID, l.Relation(), l.Issue.Key)
+	}
+
+	b = []byte(`{"key": "TEST-2", "fields": {
+		"issuelinks": [{"id": "1", "type": {"name": "Duplicate", "inward": "is duplicated by", "outward": "duplicates"}, "inwardIssue": {"key": "TEST-3"}}],
+		"subtasks": [{"id": "10003", "key": "TEST-4", "fields": {"summary": "Buy paper", "status": {"name": "Open"}}}]
+	}}`)
+	is = Issue{}
+	if err := json.Unmarshal(b, &is); err != nil {
+		t.Fatal(err)
+	}
+	if len(is.Links) != 1 || is.Links[0].Relation() != "is duplicated by" || is.Links[0].Issue.Key != "TEST-3" {
+		t.Errorf("got links %+v, want TEST-2 is duplicated by TEST-3", is.Links)
+	}
+	if len(is.Subtasks) != 1 || is.Subtasks[0].Key != "TEST-4" || is.Subtasks[0].Summary != "Buy paper" {
+		t.Errorf("got subtasks %+v, want TEST-4", is.Subtasks)
+	}
 }
-*/
@@ -0,0 +1,649 @@
+package jiratest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"olowe.co/issues/jira"
+)
+
+func (s *Server) serveProjects(w http.ResponseWriter, req *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	projects := make([]map[string]string, len(s.projects))
+	for i, p := range s.projects {
+		projects[i] = s.projectJSON(p)
+	}
+	writeJSON(w, http.StatusOK, projects)
+}
+
+func (s *Server) serveProject(w http.ResponseWriter, req *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := s.project(req.PathValue("key"))
+	if p == nil {
+		writeError(w, http.StatusNotFound, "No project could be found with key '"+req.PathValue("key")+"'.")
+		return
+	}
+	writeJSON(w, http.StatusOK, s.projectJSON(p))
+}
+
+func (s *Server) serveSearch(w http.ResponseWriter, req *http.Request) {
+	startAt, maxResults := 0, MaxResults
+	var err error
+	if v := req.FormValue("startAt"); v != "" {
+		startAt, err = strconv.Atoi(v)
+		if err != nil || startAt < 0 {
+			writeError(w, http.StatusBadRequest, "bad startAt")
+			return
+		}
+	}
+	if v := req.FormValue("maxResults"); v != "" {
+		maxResults, err = strconv.Atoi(v)
+		if err != nil || maxResults < 0 {
+			writeError(w, http.StatusBadRequest, "bad maxResults")
+			return
+		}
+		maxResults = min(maxResults, MaxResults)
+	}
+	q, err := jira.ParseQuery(req.FormValue("jql"))
+	if err == nil {
+		err = q.Check()
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Error in the JQL Query: "+err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	env := &jira.QueryEnv{}
+	if u := requestUser(req); u != nil {
+		env.User = u.Name
+	}
+	type result struct {
+		is *issue
+		v  *jira.Issue
+	}
+	var matched []result
+	for _, is := range s.issues {
+		v, err := s.decode(is)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if q.Match(v, env) {
+			matched = append(matched, result{is, v})
+		}
+	}
+	slices.SortStableFunc(matched, func(a, b result) int {
+		return q.Compare(a.v, b.v)
+	})
+	page := matched[min(startAt, len(matched)):]
+	page = page[:min(maxResults, len(page))]
+	issues := make([]map[string]any, len(page))
+	for i, is := range page {
+		issues[i] = s.issueJSON(is.is, nil)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"startAt":    startAt,
+		"maxResults": maxResults,
+		"total":      len(matched),
+		"issues":     issues,
+	})
+}
+
+func (s *Server) createIssue(w http.ResponseWriter, req *http.Request) {
+	var body struct {
+		Fields map[string]json.RawMessage
+	}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var ref struct{ ID, Key string }
+	if err := json.Unmarshal(body.Fields["project"], &ref); err != nil || (ref.Key == "" && ref.ID == "") {
+		fieldError(w, "project", "project is required")
+		return
+	}
+	p := s.project(ref.Key)
+	if p == nil {
+		p = s.project(ref.ID)
+	}
+	if p == nil {
+		fieldError(w, "project", "valid project is required")
+		return
+	}
+	if field, msg := s.checkFields(body.Fields, true); field != "" {
+		fieldError(w, field, msg)
+		return
+	}
+	is := s.addIssue(p, body.Fields, requestUser(req))
+	writeJSON(w, http.StatusCreated, map[string]string{
+		"id":   is.id,
+		"key":  is.key,
+		"self": s.URL + "/issue/" + is.id,
+	})
+}
+
+// systemFields are the built-in fields of every issue.
+var systemFields = []jira.Field{
+	{ID: "summary", Name: "Summary", Schema: jira.FieldSchema{Type: "string"}},
+	{ID: "description", Name: "Description", Schema: jira.FieldSchema{Type: "string"}},
+	{ID: "project", Name: "Project", Schema: jira.FieldSchema{Type: "project"}},
+	{ID: "issuetype", Name: "Issue Type", Schema: jira.FieldSchema{Type: "issuetype"}},
+	{ID: "status", Name: "Status", Schema: jira.FieldSchema{Type: "status"}},
+	{ID: "priority", Name: "Priority", Schema: jira.FieldSchema{Type: "priority"}},
+	{ID: "resolution", Name: "Resolution", Schema: jira.FieldSchema{Type: "resolution"}},
+	{ID: "resolutiondate", Name: "Resolved", Schema: jira.FieldSchema{Type: "datetime"}},
+	{ID: "assignee", Name: "Assignee", Schema: jira.FieldSchema{Type: "user"}},
+	{ID: "reporter", Name: "Reporter", Schema: jira.FieldSchema{Type: "user"}},
+	{ID: "labels", Name: "Labels", Schema: jira.FieldSchema{Type: "array", Items: "string"}},
+	{ID: "components", Name: "Component/s", Schema: jira.FieldSchema{Type: "array", Items: "component"}},
+	{ID: "fixVersions", Name: "Fix Version/s", Schema: jira.FieldSchema{Type: "array", Items: "version"}},
+	{ID: "versions", Name: "Affects Version/s", Schema: jira.FieldSchema{Type: "array", Items: "version"}},
+	{ID: "duedate", Name: "Due Date", Schema: jira.FieldSchema{Type: "date"}},
+	{ID: "created", Name: "Created", Schema: jira.FieldSchema{Type: "datetime"}},
+	{ID: "updated", Name: "Updated", Schema: jira.FieldSchema{Type: "datetime"}},
+	{ID: "comment", Name: "Comment", Schema: jira.FieldSchema{Type: "comments-page"}},
+	{ID: "issuelinks", Name: "Linked Issues", Schema: jira.FieldSchema{Type: "array", Items: "issuelinks"}},
+	{ID: "subtasks", Name: "Sub-Tasks", Schema: jira.FieldSchema{Type: "array", Items: "issuelinks"}},
+	{ID: "parent", Name: "Parent"},
+	{ID: "watches", Name: "Watchers", Schema: jira.FieldSchema{Type: "watches"}},
+}
+
+func (s *Server) serveFields(w http.ResponseWriter, req *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	writeJSON(w, http.StatusOK, slices.Concat(systemFields, s.custom))
+}
+
+// checkFields returns the name of the first invalid field in fields
+// and a message explaining why, or an empty name if all are valid.
+func (s *Server) checkFields(fields map[string]json.RawMessage, create bool) (field, msg string) {
+	if v, ok := fields["summary"]; ok || create {
+		var summary string
+		if json.Unmarshal(v, &summary) != nil || summary == "" {
+			return "summary", "You must specify a summary of the issue."
+		}
+	}
+	for k := range fields {
+		known := slices.ContainsFunc(s.custom, func(f jira.Field) bool { return f.ID == k })
+		if k == "status" || strings.HasPrefix(k, "customfield_") && !known {
+			return k, "Field '" + k + "' cannot be set. It is not on the appropriate screen, or unknown."
+		}
+	}
+	if v, ok := fields["assignee"]; ok && len(s.users) > 0 && string(v) != "null" {
+		var u struct{ Name string }
+		if json.Unmarshal(v, &u) != nil || s.users[u.Name] == nil {
+			return "assignee", "User '" + u.Name + "' does not exist."
+		}
+	}
+	return "", ""
+}
+
+func (s *Server) serveIssue(w http.ResponseWriter, req *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	is := s.issue(req.PathValue("key"))
+	if is == nil {
+		issueNotFound(w)
+		return
+	}
+	var only []string
+	if v := req.FormValue("fields"); v != "" {
+		only = strings.Split(v, ",")
+	}
+	v := s.issueJSON(is, only)
+	if slices.Contains(strings.Split(req.FormValue("expand"), ","), "changelog") {
+		history := is.history[:min(len(is.history), MaxResults)]
+		histories := make([]map[string]any, len(history))
+		for i, c := range history {
+			histories[i] = s.changeJSON(c)
+		}
+		v["changelog"] = map[string]any{
+			"startAt":    0,
+			"maxResults": MaxResults,
+			"total":      len(is.history),
+			"histories":  histories,
+		}
+	}
+	writeJSON(w, http.StatusOK, v)
+}
+
+func (s *Server) serveChangelog(w http.ResponseWriter, req *http.Request) {
+	startAt, maxResults := 0, MaxResults
+	var err error
+	if v := req.FormValue("startAt"); v != "" {
+		startAt, err = strconv.Atoi(v)
+		if err != nil || startAt < 0 {
+			writeError(w, http.StatusBadRequest, "bad startAt")
+			return
+		}
+	}
+	if v := req.FormValue("maxResults"); v != "" {
+		maxResults, err = strconv.Atoi(v)
+		if err != nil || maxResults < 0 {
+			writeError(w, http.StatusBadRequest, "bad maxResults")
+			return
+		}
+		maxResults = min(maxResults, MaxResults)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	is := s.issue(req.PathValue("key"))
+	if is == nil {
+		issueNotFound(w)
+		return
+	}
+	page := is.history[min(startAt, len(is.history)):]
+	page = page[:min(maxResults, len(page))]
+	values := make([]map[string]any, len(page))
+	for i, c := range page {
+		values[i] = s.changeJSON(c)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"startAt":    startAt,
+		"maxResults": maxResults,
+		"total":      len(is.history),
+		"isLast":     startAt+len(page) >= len(is.history),
+		"values":     values,
+	})
+}
+
+func issueNotFound(w http.ResponseWriter) {
+	writeError(w, http.StatusNotFound, "Issue does not exist or you do not have permission to see it.")
+}
+
+func (s *Server) updateIssue(w http.ResponseWriter, req *http.Request) {
+	var edit struct {
+		Fields map[string]json.RawMessage
+	}
+	if err := json.NewDecoder(req.Body).Decode(&edit); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	is := s.issue(req.PathValue("key"))
+	if is == nil {
+		issueNotFound(w)
+		return
+	}
+	if field, msg := s.checkFields(edit.Fields, false); field != "" {
+		fieldError(w, field, msg)
+		return
+	}
+	old := maps.Clone(is.fields)
+	for k, v := range edit.Fields {
+		is.fields[k] = v
+	}
+	s.expandUsers(is.fields)
+	keys := make([]string, 0, len(edit.Fields))
+	for k := range edit.Fields {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	var items []jira.ChangeItem
+	for _, k := range keys {
+		if !bytes.Equal(old[k], is.fields[k]) {
+			items = append(items, s.changeItem(k, old[k], is.fields[k]))
+		}
+	}
+	s.record(is, req, items)
+	is.touch()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// transitions are the workflow transitions of every issue.
+// Those leading to an issue's current status are not available.
+// Moving an issue to Done sets its resolution.
+var transitions = []jira.Transition{
+	{ID: "11", Name: "Stop Progress", To: jira.Status{Name: "Open"}},
+	{ID: "21", Name: "Start Progress", To: jira.Status{Name: "In Progress"}},
+	{ID: "31", Name: "Done", To: jira.Status{Name: "Done"}},
+}
+
+func (s *Server) serveTransitions(w http.ResponseWriter, req *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	is := s.issue(req.PathValue("key"))
+	if is == nil {
+		issueNotFound(w)
+		return
+	}
+	var status jira.Status
+	json.Unmarshal(is.fields["status"], &status)
+	var available []jira.Transition
+	for _, t := range transitions {
+		if !strings.EqualFold(t.To.Name, status.Name) {
+			available = append(available, t)
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"transitions": available})
+}
+
+func (s *Server) transition(w http.ResponseWriter, req *http.Request) {
+	var body struct {
+		Transition struct {
+			ID string
+		}
+	}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	is := s.issue(req.PathValue("key"))
+	if is == nil {
+		issueNotFound(w)
+		return
+	}
+	for _, t := range transitions {
+		if t.ID == body.Transition.ID {
+			old := maps.Clone(is.fields)
+			is.fields["status"] = mustMarshal(t.To)
+			// Like the default Jira workflow, issues are resolved
+			// when done and unresolved when reopened.
+			if t.To.Name == "Done" {
+				is.fields["resolution"] = json.RawMessage(`{"id": "10000", "name": "Done"}`)
+				is.fields["resolutiondate"] = mustMarshal(time.Now().Format(timestamp))
+			} else {
+				delete(is.fields, "resolution")
+				delete(is.fields, "resolutiondate")
+			}
+			var items []jira.ChangeItem
+			for _, k := range []string{"status", "resolution"} {
+				if !bytes.Equal(old[k], is.fields[k]) {
+					items = append(items, s.changeItem(k, old[k], is.fields[k]))
+				}
+			}
+			s.record(is, req, items)
+			is.touch()
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+	writeError(w, http.StatusBadRequest, "Transition id '"+body.Transition.ID+"' is not valid for this issue.")
+}
+
+func (s *Server) serveComments(w http.ResponseWriter, req *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	is := s.issue(req.PathValue("key"))
+	if is == nil {
+		issueNotFound(w)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.commentsJSON(is))
+}
+
+func (s *Server) createComment(w http.ResponseWriter, req *http.Request) {
+	var body struct {
+		Body string
+	}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if body.Body == "" {
+		fieldError(w, "comment", "Comment body can not be empty!")
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	is := s.issue(req.PathValue("key"))
+	if is == nil {
+		issueNotFound(w)
+		return
+	}
+	var author user
+	if u := requestUser(req); u != nil {
+		author = *u
+	}
+	c := s.addComment(is, author.User, body.Body)
+	writeJSON(w, http.StatusCreated, s.commentJSON(is, c))
+}
+
+// comment returns the issue named by the request path
+// and the index of the named comment in its comments.
+// If either is not found, comment responds with an error
+// and returns a nil issue.
+func (s *Server) comment(w http.ResponseWriter, req *http.Request) (*issue, int) {
+	is := s.issue(req.PathValue("key"))
+	if is == nil {
+		issueNotFound(w)
+		return nil, 0
+	}
+	for i, c := range is.comments {
+		if c.id == req.PathValue("id") {
+			return is, i
+		}
+	}
+	writeError(w, http.StatusNotFound, "Can not find a comment for the id: "+req.PathValue("id")+".")
+	return nil, 0
+}
+
+// mayEdit reports whether the user authenticated by req may edit c,
+// responding with an error if not.
+func mayEdit(w http.ResponseWriter, req *http.Request, c *comment) bool {
+	if u := requestUser(req); u != nil && u.Name != c.author.Name {
+		writeError(w, http.StatusForbidden, "You do not have the permission to edit this comment.")
+		return false
+	}
+	return true
+}
+
+func (s *Server) serveComment(w http.ResponseWriter, req *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	is, i := s.comment(w, req)
+	if is == nil {
+		return
+	}
+	writeJSON(w, http.StatusOK, s.commentJSON(is, is.comments[i]))
+}
+
+func (s *Server) updateComment(w http.ResponseWriter, req *http.Request) {
+	var body struct {
+		Body string
+	}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	is, i := s.comment(w, req)
+	if is == nil {
+		return
+	}
+	c := is.comments[i]
+	if !mayEdit(w, req, c) {
+		return
+	}
+	c.body = body.Body
+	c.updated = time.Now()
+	is.touch()
+	writeJSON(w, http.StatusOK, s.commentJSON(is, c))
+}
+
+func (s *Server) deleteComment(w http.ResponseWriter, req *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	is, i := s.comment(w, req)
+	if is == nil {
+		return
+	}
+	if !mayEdit(w, req, is.comments[i]) {
+		return
+	}
+	is.comments = slices.Delete(is.comments, i, i+1)
+	is.touch()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// LinkTypes are the types of links which may be made between issues,
+// as in a new Jira installation.
+var LinkTypes = []jira.LinkType{
+	{ID: "10000", Name: "Blocks", Inward: "is blocked by", Outward: "blocks"},
+	{ID: "10001", Name: "Cloners", Inward: "is cloned by", Outward: "clones"},
+	{ID: "10002", Name: "Duplicate", Inward: "is duplicated by", Outward: "duplicates"},
+	{ID: "10003", Name: "Relates", Inward: "relates to", Outward: "relates to"},
+}
+
+func (s *Server) serveLinkTypes(w http.ResponseWriter, req *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{"issueLinkTypes": LinkTypes})
+}
+
+func (s *Server) createLink(w http.ResponseWriter, req *http.Request) {
+	type ref struct{ ID, Key, Name string }
+	var body struct {
+		Type         ref
+		InwardIssue  ref
+		OutwardIssue ref
+	}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	i := slices.IndexFunc(LinkTypes, func(t jira.LinkType) bool {
+		return t.ID == body.Type.ID || strings.EqualFold(t.Name, body.Type.Name)
+	})
+	if i < 0 {
+		writeError(w, http.StatusNotFound, "No issue link type with name '"+body.Type.Name+"' found.")
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// The inward issue is the one at the inward end of the link;
+	// it relates to the outward issue by the outward description.
+	from := s.issue(body.InwardIssue.Key)
+	if from == nil {
+		from = s.issue(body.InwardIssue.ID)
+	}
+	to := s.issue(body.OutwardIssue.Key)
+	if to == nil {
+		to = s.issue(body.OutwardIssue.ID)
+	}
+	if from == nil || to == nil {
+		issueNotFound(w)
+		return
+	}
+	if from == to {
+		writeError(w, http.StatusBadRequest, "You cannot link an issue to itself.")
+		return
+	}
+	s.links = append(s.links, &link{id: s.newID(), typ: LinkTypes[i], from: from, to: to})
+	from.touch()
+	to.touch()
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (s *Server) deleteLink(w http.ResponseWriter, req *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.links, func(l *link) bool { return l.id == req.PathValue("id") })
+	if i < 0 {
+		writeError(w, http.StatusNotFound, "No issue link with id '"+req.PathValue("id")+"' exists.")
+		return
+	}
+	l := s.links[i]
+	s.links = slices.Delete(s.links, i, i+1)
+	l.from.touch()
+	l.to.touch()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) createAttachment(w http.ResponseWriter, req *http.Request) {
+	if req.Header.Get("X-Atlassian-Token") != "no-check" {
+		writeError(w, http.StatusForbidden, "XSRF check failed")
+		return
+	}
+	file, header, err := req.FormFile("file")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	is := s.issue(req.PathValue("key"))
+	if is == nil {
+		issueNotFound(w)
+		return
+	}
+	var author jira.User
+	if u := requestUser(req); u != nil {
+		author = u.User
+	}
+	a := s.addAttachment(is, author, header.Filename, data)
+	writeJSON(w, http.StatusOK, []map[string]any{s.attachmentJSON(a)})
+}
+
+func (s *Server) serveAttachment(w http.ResponseWriter, req *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, is := range s.issues {
+		for _, a := range is.attachments {
+			if a.ID == req.PathValue("id") {
+				w.Header().Set("Content-Type", a.MimeType)
+				w.Write(a.data)
+				return
+			}
+		}
+	}
+	writeError(w, http.StatusNotFound, "The attachment with id '"+req.PathValue("id")+"' does not exist.")
+}
+
+func (s *Server) serveMyself(w http.ResponseWriter, req *http.Request) {
+	u := requestUser(req)
+	if u == nil {
+		writeError(w, http.StatusUnauthorized, "You are not authenticated. Authentication required to perform this operation.")
+		return
+	}
+	writeJSON(w, http.StatusOK, u.User)
+}
+
+// searchUsers lists the users whose name, display name or address
+// starts with the query parameter, as on Jira Cloud,
+// or the username parameter, as on Jira Server, ignoring case.
+func (s *Server) searchUsers(w http.ResponseWriter, req *http.Request) {
+	q := req.FormValue("query")
+	if q == "" {
+		q = req.FormValue("username")
+	}
+	if q == "" {
+		writeError(w, http.StatusBadRequest, "The query parameter was not provided.")
+		return
+	}
+	q = strings.ToLower(q)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	found := []jira.User{}
+	for _, u := range s.users {
+		for _, v := range []string{u.Name, u.DisplayName, u.Email} {
+			if strings.HasPrefix(strings.ToLower(v), q) {
+				found = append(found, u.User)
+				break
+			}
+		}
+	}
+	slices.SortFunc(found, func(a, b jira.User) int { return strings.Compare(a.Name, b.Name) })
+	writeJSON(w, http.StatusOK, found)
+}
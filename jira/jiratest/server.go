@@ -0,0 +1,693 @@
+// Package jiratest provides an in-memory Jira server for testing
+// programs using the Jira REST API, such as those using package jira.
+//
+// The server implements the subset of the Jira REST API version 2
+// used by package jira.
+// Projects, issues and custom fields may be added directly with
+// AddProject, AddIssue, AddComment, AddAttachment and AddField,
+// or by clients through the API, which may also edit, link
+// and attach files to issues, and create, edit and delete comments.
+// Issues may be removed, as if by another user, with DeleteIssue.
+// Issues may be linked with the link types in LinkTypes.
+// Edits and transitions made through the API are recorded
+// in the issue's changelog.
+// Like Jira Cloud, the changelog included in an issue
+// holds at most MaxResults changes;
+// the rest must be read from the paginated changelog.
+// Searches are evaluated by jira.Query.Match,
+// so are limited to the queries accepted by jira.Query.Check.
+package jiratest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"olowe.co/issues/jira"
+)
+
+// timestamp is the layout of times in Jira API responses.
+const timestamp = "2006-01-02T15:04:05.000-0700"
+
+// MaxResults is the most issues returned in one page of search results,
+// or changes in one page of a changelog,
+// regardless of the number requested.
+const MaxResults = 50
+
+// Server is an in-memory Jira server listening on the loopback interface.
+// It is safe for concurrent use.
+//
+// Until a user is added with AddUser, requests need no authentication.
+// Once any user is added, every request must authenticate
+// as a known user with HTTP basic authentication using the user's password,
+// or with one of the user's tokens added by AddToken as a bearer token.
+// Authenticated users may only edit and delete their own comments.
+type Server struct {
+	// URL is the root of the server's REST API,
+	// such as http://127.0.0.1:54321/rest/api/2.
+	URL string
+
+	srv *httptest.Server
+
+	mu       sync.Mutex
+	projects []*project
+	issues   []*issue
+	users    map[string]*user
+	tokens   map[string]*user
+	custom   []jira.Field
+	links    []*link
+	nextID   int
+}
+
+type project struct {
+	id, key, name string
+}
+
+type issue struct {
+	id, key     string
+	fields      map[string]json.RawMessage
+	comments    []*comment
+	history     []*change
+	attachments []*attachment
+}
+
+type attachment struct {
+	jira.Attachment
+	data []byte
+}
+
+// change is an entry in an issue's changelog.
+type change struct {
+	id      string
+	author  jira.User
+	created time.Time
+	items   []jira.ChangeItem
+}
+
+// link records that from relates to to
+// by the outward description of typ.
+type link struct {
+	id       string
+	typ      jira.LinkType
+	from, to *issue
+}
+
+type comment struct {
+	id               string
+	body             string
+	author           jira.User
+	created, updated time.Time
+}
+
+type user struct {
+	jira.User
+	password string
+}
+
+const apiPath = "/rest/api/2"
+
+// NewServer starts and returns a new empty Server.
+// The caller should call Close when finished, to shut it down.
+func NewServer() *Server {
+	s := &Server{
+		users:  make(map[string]*user),
+		tokens: make(map[string]*user),
+		nextID: 10000,
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+apiPath+"/project", s.serveProjects)
+	mux.HandleFunc("GET "+apiPath+"/project/{key}", s.serveProject)
+	mux.HandleFunc("GET "+apiPath+"/search", s.serveSearch)
+	mux.HandleFunc("POST "+apiPath+"/issue", s.createIssue)
+	mux.HandleFunc("GET "+apiPath+"/issue/{key}", s.serveIssue)
+	mux.HandleFunc("PUT "+apiPath+"/issue/{key}", s.updateIssue)
+	mux.HandleFunc("GET "+apiPath+"/issue/{key}/changelog", s.serveChangelog)
+	mux.HandleFunc("POST "+apiPath+"/issue/{key}/attachments", s.createAttachment)
+	mux.HandleFunc("GET /secure/attachment/{id}/{name}", s.serveAttachment)
+	mux.HandleFunc("GET "+apiPath+"/issue/{key}/transitions", s.serveTransitions)
+	mux.HandleFunc("POST "+apiPath+"/issue/{key}/transitions", s.transition)
+	mux.HandleFunc("GET "+apiPath+"/issue/{key}/comment", s.serveComments)
+	mux.HandleFunc("POST "+apiPath+"/issue/{key}/comment", s.createComment)
+	mux.HandleFunc("GET "+apiPath+"/issue/{key}/comment/{id}", s.serveComment)
+	mux.HandleFunc("PUT "+apiPath+"/issue/{key}/comment/{id}", s.updateComment)
+	mux.HandleFunc("DELETE "+apiPath+"/issue/{key}/comment/{id}", s.deleteComment)
+	mux.HandleFunc("GET "+apiPath+"/myself", s.serveMyself)
+	mux.HandleFunc("GET "+apiPath+"/user/search", s.searchUsers)
+	mux.HandleFunc("GET "+apiPath+"/field", s.serveFields)
+	mux.HandleFunc("GET "+apiPath+"/issueLinkType", s.serveLinkTypes)
+	mux.HandleFunc("POST "+apiPath+"/issueLink", s.createLink)
+	mux.HandleFunc("DELETE "+apiPath+"/issueLink/{id}", s.deleteLink)
+	s.srv = httptest.NewServer(s.authenticate(mux))
+	s.URL = s.srv.URL + apiPath
+	return s
+}
+
+// Close shuts down the server and blocks until all outstanding requests
+// on this server have completed.
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// Client returns a jira.Client for the server without authentication.
+// Set its Auth field to authenticate as a user.
+func (s *Server) Client() *jira.Client {
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		panic(err)
+	}
+	return &jira.Client{Client: s.srv.Client(), APIRoot: u}
+}
+
+// AddUser adds a user who may authenticate with the given password.
+// The user may be assigned issues by name,
+// and searched for by name, display name or mail address.
+func (s *Server) AddUser(u jira.User, password string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[u.Name] = &user{u, password}
+}
+
+// AddToken adds a bearer token authenticating the named user,
+// which must have been added with AddUser.
+func (s *Server) AddToken(name, token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[name]
+	if !ok {
+		panic("jiratest: add token for unknown user " + name)
+	}
+	s.tokens[token] = u
+}
+
+// AddField adds a custom field with the given name and schema,
+// and returns its ID, such as customfield_10000.
+// Values of custom fields may then be set in issues by ID.
+func (s *Server) AddField(name string, schema jira.FieldSchema) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f := jira.Field{ID: "customfield_" + s.newID(), Name: name, Custom: true, Schema: schema}
+	s.custom = append(s.custom, f)
+	return f.ID
+}
+
+// AddProject adds a project with the given key and name.
+func (s *Server) AddProject(key, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.addProject(key, name)
+}
+
+func (s *Server) addProject(key, name string) *project {
+	if p := s.project(key); p != nil {
+		return p
+	}
+	p := &project{id: s.newID(), key: key, name: name}
+	s.projects = append(s.projects, p)
+	return p
+}
+
+// AddIssue adds an issue to the project with the given key,
+// adding the project first if needed, and returns the new issue's key.
+// Fields holds the issue's fields keyed by field ID,
+// in the form they are sent to Jira, such as
+//
+//	map[string]any{
+//		"summary":  "Crash on startup",
+//		"status":   map[string]string{"name": "In Progress"},
+//		"assignee": map[string]string{"name": "fred"},
+//		"updated":  time.Now().Add(-time.Hour),
+//	}
+//
+// Values of type time.Time are encoded as Jira does.
+// The status defaults to Open, the issue type to Task, the priority to Medium,
+// and the created and updated times to now.
+// AddIssue panics if fields cannot be encoded as JSON.
+func (s *Server) AddIssue(projectKey string, fields map[string]any) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := s.addProject(projectKey, projectKey)
+	raw := make(map[string]json.RawMessage)
+	for k, v := range fields {
+		if t, ok := v.(time.Time); ok {
+			v = t.Format(timestamp)
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			panic(fmt.Sprintf("jiratest: encode field %s: %v", k, err))
+		}
+		raw[k] = b
+	}
+	return s.addIssue(p, raw, nil).key
+}
+
+// AddComment adds a comment by author to the issue with the given key
+// and returns the comment's ID.
+// AddComment panics if there is no such issue.
+func (s *Server) AddComment(key string, author jira.User, body string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	is := s.issue(key)
+	if is == nil {
+		panic("jiratest: add comment to unknown issue " + key)
+	}
+	return s.addComment(is, author, body).id
+}
+
+// AddAttachment attaches a file with the given name and contents,
+// uploaded by author, to the issue with the given key
+// and returns the attachment's ID.
+// AddAttachment panics if there is no such issue.
+func (s *Server) AddAttachment(key string, author jira.User, name string, data []byte) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	is := s.issue(key)
+	if is == nil {
+		panic("jiratest: add attachment to unknown issue " + key)
+	}
+	return s.addAttachment(is, author, name, data).ID
+}
+
+// DeleteIssue removes the issue with the given key and its links,
+// as if deleted by another user.
+// DeleteIssue panics if there is no such issue.
+func (s *Server) DeleteIssue(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	is := s.issue(key)
+	if is == nil {
+		panic("jiratest: delete unknown issue " + key)
+	}
+	s.issues = slices.DeleteFunc(s.issues, func(i *issue) bool { return i == is })
+	s.links = slices.DeleteFunc(s.links, func(l *link) bool {
+		if l.from == is || l.to == is {
+			l.from.touch()
+			l.to.touch()
+			return true
+		}
+		return false
+	})
+}
+
+func (s *Server) addAttachment(is *issue, author jira.User, name string, data []byte) *attachment {
+	id := s.newID()
+	a := &attachment{
+		Attachment: jira.Attachment{
+			ID:       id,
+			Filename: name,
+			Author:   author,
+			Created:  time.Now(),
+			Size:     int64(len(data)),
+			MimeType: http.DetectContentType(data),
+			Content:  s.srv.URL + "/secure/attachment/" + id + "/" + url.PathEscape(name),
+		},
+		data: data,
+	}
+	is.attachments = append(is.attachments, a)
+	is.touch()
+	return a
+}
+
+func (s *Server) newID() string {
+	s.nextID++
+	return strconv.Itoa(s.nextID)
+}
+
+func (s *Server) project(key string) *project {
+	for _, p := range s.projects {
+		if strings.EqualFold(p.key, key) || p.id == key {
+			return p
+		}
+	}
+	return nil
+}
+
+// issue returns the issue with the given key or ID.
+func (s *Server) issue(key string) *issue {
+	for _, is := range s.issues {
+		if is.key == key || is.id == key {
+			return is
+		}
+	}
+	return nil
+}
+
+// addIssue stores a new issue in p with the given fields,
+// filling in those Jira sets itself.
+func (s *Server) addIssue(p *project, fields map[string]json.RawMessage, reporter *user) *issue {
+	var n int
+	for _, is := range s.issues {
+		_, num, _ := strings.Cut(is.key, "-")
+		if i, err := strconv.Atoi(num); err == nil && strings.HasPrefix(is.key, p.key+"-") {
+			n = max(n, i)
+		}
+	}
+	is := &issue{id: s.newID(), key: fmt.Sprintf("%s-%d", p.key, n+1), fields: fields}
+	now := mustMarshal(time.Now().Format(timestamp))
+	defaults := map[string]json.RawMessage{
+		"project":   mustMarshal(s.projectJSON(p)),
+		"status":    json.RawMessage(`{"name": "Open"}`),
+		"issuetype": json.RawMessage(`{"name": "Task"}`),
+		"priority":  json.RawMessage(`{"id": "3", "name": "Medium"}`),
+		"created":   now,
+		"updated":   now,
+	}
+	if reporter != nil {
+		defaults["reporter"] = mustMarshal(reporter.User)
+	}
+	for k, v := range defaults {
+		if _, ok := fields[k]; !ok || k == "project" {
+			fields[k] = v
+		}
+	}
+	s.expandUsers(fields)
+	s.issues = append(s.issues, is)
+	return is
+}
+
+func (s *Server) addComment(is *issue, author jira.User, body string) *comment {
+	now := time.Now()
+	c := &comment{id: s.newID(), body: body, author: author, created: now, updated: now}
+	is.comments = append(is.comments, c)
+	is.touch()
+	return c
+}
+
+// touch sets the issue's updated time to now.
+func (is *issue) touch() {
+	is.fields["updated"] = mustMarshal(time.Now().Format(timestamp))
+}
+
+// expandUsers replaces users in fields named only by name
+// with the details of a known user of that name.
+// The fields are the assignee, reporter and custom fields of users.
+func (s *Server) expandUsers(fields map[string]json.RawMessage) {
+	keys := []string{"assignee", "reporter"}
+	for _, f := range s.custom {
+		if f.Schema.Type == "user" {
+			keys = append(keys, f.ID)
+		}
+	}
+	for _, k := range keys {
+		var u jira.User
+		if json.Unmarshal(fields[k], &u) != nil || u.Name == "" {
+			continue
+		}
+		if known, ok := s.users[u.Name]; ok {
+			fields[k] = mustMarshal(known.User)
+		}
+	}
+}
+
+func mustMarshal(v any) json.RawMessage {
+	b, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func (s *Server) projectJSON(p *project) map[string]string {
+	return map[string]string{
+		"id":   p.id,
+		"key":  p.key,
+		"name": p.name,
+		"self": s.URL + "/project/" + p.id,
+	}
+}
+
+func (s *Server) commentJSON(is *issue, c *comment) map[string]any {
+	return map[string]any{
+		"id":           c.id,
+		"self":         fmt.Sprintf("%s/issue/%s/comment/%s", s.URL, is.id, c.id),
+		"body":         c.body,
+		"author":       c.author,
+		"updateAuthor": c.author,
+		"created":      c.created.Format(timestamp),
+		"updated":      c.updated.Format(timestamp),
+	}
+}
+
+func (s *Server) commentsJSON(is *issue) map[string]any {
+	comments := make([]map[string]any, len(is.comments))
+	for i, c := range is.comments {
+		comments[i] = s.commentJSON(is, c)
+	}
+	return map[string]any{
+		"comments":   comments,
+		"startAt":    0,
+		"maxResults": len(comments),
+		"total":      len(comments),
+	}
+}
+
+// issueJSON returns the representation of is in API responses.
+// If only is not empty, only the named fields are included.
+func (s *Server) issueJSON(is *issue, only []string) map[string]any {
+	fields := make(map[string]any)
+	for k, v := range is.fields {
+		fields[k] = v
+	}
+	fields["comment"] = s.commentsJSON(is)
+	fields["issuelinks"] = s.linksJSON(is)
+	attachments := make([]map[string]any, len(is.attachments))
+	for i, a := range is.attachments {
+		attachments[i] = s.attachmentJSON(a)
+	}
+	fields["attachment"] = attachments
+	if len(only) > 0 {
+		for k := range fields {
+			if !slices.Contains(only, k) {
+				delete(fields, k)
+			}
+		}
+	}
+	return map[string]any{
+		"id":     is.id,
+		"key":    is.key,
+		"self":   s.URL + "/issue/" + is.id,
+		"fields": fields,
+	}
+}
+
+func (s *Server) attachmentJSON(a *attachment) map[string]any {
+	return map[string]any{
+		"id":       a.ID,
+		"self":     s.URL + "/attachment/" + a.ID,
+		"filename": a.Filename,
+		"author":   a.Author,
+		"created":  a.Created.Format(timestamp),
+		"size":     a.Size,
+		"mimeType": a.MimeType,
+		"content":  a.Content,
+	}
+}
+
+func (s *Server) changeJSON(c *change) map[string]any {
+	return map[string]any{
+		"id":      c.id,
+		"author":  c.author,
+		"created": c.created.Format(timestamp),
+		"items":   c.items,
+	}
+}
+
+// record adds a change by the user authenticated by req
+// to the changelog of is, unless there are no items.
+func (s *Server) record(is *issue, req *http.Request, items []jira.ChangeItem) {
+	if len(items) == 0 {
+		return
+	}
+	var author jira.User
+	if u := requestUser(req); u != nil {
+		author = u.User
+	}
+	is.history = append(is.history, &change{id: s.newID(), author: author, created: time.Now(), items: items})
+}
+
+// changeItem returns the changelog entry for setting the field id
+// from old to new, either of which may be missing.
+func (s *Server) changeItem(id string, old, new json.RawMessage) jira.ChangeItem {
+	it := jira.ChangeItem{Field: id, FieldType: "jira"}
+	for _, f := range s.custom {
+		if f.ID == id {
+			it.Field, it.FieldType = f.Name, "custom"
+		}
+	}
+	it.From, it.FromString = changeValue(id, old)
+	it.To, it.ToString = changeValue(id, new)
+	return it
+}
+
+// changeValue returns the ID and text of a field's value
+// as recorded in changelogs.
+// Only users have IDs: their names.
+func changeValue(field string, v json.RawMessage) (id, text string) {
+	var x any
+	if len(v) == 0 || json.Unmarshal(v, &x) != nil {
+		return "", ""
+	}
+	switch x := x.(type) {
+	case string:
+		return "", x
+	case float64:
+		return "", strconv.FormatFloat(x, 'f', -1, 64)
+	case []any:
+		var elems []string
+		for _, e := range x {
+			if _, s := changeValue(field, mustMarshal(e)); s != "" {
+				elems = append(elems, s)
+			}
+		}
+		// Like Jira, labels are separated by spaces.
+		if field == "labels" {
+			return "", strings.Join(elems, " ")
+		}
+		return "", strings.Join(elems, ", ")
+	case map[string]any:
+		if name, ok := x["displayName"].(string); ok {
+			id, _ := x["name"].(string)
+			return id, name
+		}
+		for _, k := range []string{"name", "value", "key"} {
+			if s, ok := x[k].(string); ok {
+				return "", s
+			}
+		}
+	}
+	return "", ""
+}
+
+// linksJSON returns the links of is as listed in its issuelinks field.
+// Each names the issue at the other end of the link:
+// as outwardIssue if is relates to it by the outward description of the type,
+// otherwise as inwardIssue.
+func (s *Server) linksJSON(is *issue) []map[string]any {
+	links := []map[string]any{}
+	for _, l := range s.links {
+		v := map[string]any{
+			"id":   l.id,
+			"self": s.URL + "/issueLink/" + l.id,
+			"type": l.typ,
+		}
+		switch is {
+		case l.from:
+			v["outwardIssue"] = s.linkedIssueJSON(l.to)
+		case l.to:
+			v["inwardIssue"] = s.linkedIssueJSON(l.from)
+		default:
+			continue
+		}
+		links = append(links, v)
+	}
+	return links
+}
+
+// linkedIssueJSON returns the summary of is included in links to it.
+func (s *Server) linkedIssueJSON(is *issue) map[string]any {
+	fields := make(map[string]json.RawMessage)
+	for _, k := range []string{"summary", "status", "priority", "issuetype"} {
+		if v, ok := is.fields[k]; ok {
+			fields[k] = v
+		}
+	}
+	return map[string]any{
+		"id":     is.id,
+		"key":    is.key,
+		"self":   s.URL + "/issue/" + is.id,
+		"fields": fields,
+	}
+}
+
+// decode returns is as decoded by package jira.
+func (s *Server) decode(is *issue) (*jira.Issue, error) {
+	var v jira.Issue
+	if err := json.Unmarshal(mustMarshal(s.issueJSON(is, nil)), &v); err != nil {
+		return nil, fmt.Errorf("decode issue %s: %w", is.key, err)
+	}
+	return &v, nil
+}
+
+type contextKey struct{}
+
+// authenticate checks the credentials of each request
+// before passing it to next.
+// The authenticated user, if any, is stored in the request's context.
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		s.mu.Lock()
+		u, err := s.requestUser(req)
+		s.mu.Unlock()
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Basic realm="jiratest"`)
+			writeError(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+		if u != nil {
+			ctx := context.WithValue(req.Context(), contextKey{}, u)
+			req = req.WithContext(ctx)
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+// requestUser returns the user authenticated by req,
+// or nil if no users are known.
+func (s *Server) requestUser(req *http.Request) (*user, error) {
+	if len(s.users) == 0 {
+		return nil, nil
+	}
+	if name, password, ok := req.BasicAuth(); ok {
+		u, ok := s.users[name]
+		if !ok || u.password != password {
+			return nil, errors.New("bad username or password")
+		}
+		return u, nil
+	}
+	if token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer "); ok {
+		u, ok := s.tokens[token]
+		if !ok {
+			return nil, errors.New("bad token")
+		}
+		return u, nil
+	}
+	return nil, errors.New("authentication required")
+}
+
+func requestUser(req *http.Request) *user {
+	u, _ := req.Context().Value(contextKey{}).(*user)
+	return u
+}
+
+// writeError responds with status and a Jira error body
+// holding the given messages.
+func writeError(w http.ResponseWriter, status int, messages ...string) {
+	writeJSON(w, status, map[string]any{"errorMessages": messages, "errors": map[string]string{}})
+}
+
+// fieldError responds with status 400 Bad Request
+// and a Jira error body reporting msg for the named field.
+func fieldError(w http.ResponseWriter, field, msg string) {
+	body := map[string]any{
+		"errorMessages": []string{},
+		"errors":        map[string]string{field: msg},
+	}
+	writeJSON(w, http.StatusBadRequest, body)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Println("jiratest: encode response:", err)
+	}
+}
@@ -0,0 +1,394 @@
+package jiratest
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"olowe.co/issues/jira"
+)
+
+func TestServer(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	srv.AddProject("TEST", "Test project")
+	client := srv.Client()
+
+	created, err := client.CreateIssue(&jira.Issue{Project: jira.Project{Key: "TEST"}, Summary: "hello"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.Key != "TEST-1" {
+		t.Errorf("created issue %s, want TEST-1", created.Key)
+	}
+	if _, err := client.CreateIssue(&jira.Issue{Project: jira.Project{Key: "NOPE"}, Summary: "hello"}); err == nil {
+		t.Error("created issue in unknown project")
+	}
+	if err := client.UpdateIssue("TEST-1", map[string]any{"summary": "goodbye"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Transition("TEST-1", "In Progress"); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.PostComment("TEST-1", strings.NewReader("first!")); err != nil {
+		t.Fatal(err)
+	}
+	issue, err := client.Issue("TEST-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issue.Summary != "goodbye" || issue.Status.Name != "In Progress" || len(issue.Comments) != 1 {
+		t.Errorf("unexpected issue after edits: %+v", issue)
+	}
+	if issue.Priority.Name != "Medium" || issue.Project.Title != "Test project" {
+		t.Errorf("got priority %q in project %q, want Medium in Test project", issue.Priority.Name, issue.Project.Title)
+	}
+	if err := client.Transition("TEST-1", "Done"); err != nil {
+		t.Fatal(err)
+	}
+	if done, err := client.Issue("TEST-1"); err != nil || done.Resolution.Name != "Done" || done.Resolved.IsZero() {
+		t.Errorf("done issue not resolved: %v, %v", done, err)
+	}
+	id := issue.Comments[0].ID
+	if err := client.UpdateComment("TEST-1", id, strings.NewReader("second!")); err != nil {
+		t.Fatal(err)
+	}
+	if c, err := client.Comment("TEST-1", id); err != nil || c.Body != "second!" {
+		t.Errorf("get updated comment: got %v, %v", c, err)
+	}
+	if err := client.DeleteComment("TEST-1", id); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Comment("TEST-1", id); !errors.Is(err, jira.ErrNotFound) {
+		t.Errorf("want ErrNotFound for deleted comment, got %v", err)
+	}
+
+	// The server works as a backend for the filesystem.
+	fsys := &jira.FS{Client: client}
+	b, err := fs.ReadFile(fsys, "TEST/1/issue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), "Subject: goodbye") {
+		t.Errorf("issue file missing subject:\n%s", b)
+	}
+
+	srv.DeleteIssue("TEST-1")
+	if _, err := client.Issue("TEST-1"); !errors.Is(err, jira.ErrNotFound) {
+		t.Errorf("want ErrNotFound for deleted issue, got %v", err)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	fred := jira.User{Name: "fred", DisplayName: "Fred"}
+	srv.AddUser(fred, "secret")
+	srv.AddUser(jira.User{Name: "ann"}, "secret")
+	week := time.Now().Add(-7 * 24 * time.Hour)
+	srv.AddIssue("TEST", map[string]any{"summary": "one", "assignee": map[string]string{"name": "fred"}, "updated": week})
+	srv.AddIssue("TEST", map[string]any{"summary": "two", "status": map[string]string{"name": "Done"}})
+	srv.AddIssue("TEST", map[string]any{"summary": "three", "assignee": map[string]string{"name": "ann"}})
+	srv.AddIssue("WEB", map[string]any{"summary": "four", "assignee": map[string]string{"name": "fred"}})
+	client := srv.Client()
+	client.Auth = &jira.BasicAuth{Username: "fred", Password: "secret"}
+
+	var tests = []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"TEST-1", "TEST-2", "TEST-3", "WEB-1"}},
+		{"project = TEST", []string{"TEST-1", "TEST-2", "TEST-3"}},
+		{`project = "web"`, []string{"WEB-1"}},
+		{"status = Done", []string{"TEST-2"}},
+		{"status != done", []string{"TEST-1", "TEST-3", "WEB-1"}},
+		{"assignee = currentUser()", []string{"TEST-1", "WEB-1"}},
+		{"assignee != fred", []string{"TEST-3"}},
+		{"assignee is EMPTY", []string{"TEST-2"}},
+		{"key in (TEST-3, WEB-1)", []string{"TEST-3", "WEB-1"}},
+		{"updated < -1d", []string{"TEST-1"}},
+		{"project = TEST and (status = done or assignee = ann)", []string{"TEST-2", "TEST-3"}},
+		{"not project = TEST", []string{"WEB-1"}},
+		{"project = TEST order by key desc", []string{"TEST-3", "TEST-2", "TEST-1"}},
+		{"order by updated", []string{"TEST-1", "TEST-2", "TEST-3", "WEB-1"}},
+	}
+	for _, tt := range tests {
+		issues, err := client.SearchIssues(tt.query)
+		if err != nil {
+			t.Errorf("search %q: %v", tt.query, err)
+			continue
+		}
+		var got []string
+		for _, is := range issues {
+			got = append(got, is.Key)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("search %q: got %v, want %v", tt.query, got, tt.want)
+		}
+	}
+
+	for _, q := range []string{"project =", "status ~ Done", "colour = red", "updated > yesterday", "(project = TEST"} {
+		if _, err := client.SearchIssues(q); err == nil {
+			t.Errorf("search %q: no error", q)
+		}
+	}
+}
+
+func TestSearchPagination(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	for i := 0; i < MaxResults*2+1; i++ {
+		srv.AddIssue("TEST", map[string]any{"summary": fmt.Sprint("issue ", i)})
+	}
+	iter := srv.Client().Search("project = TEST")
+	var pages, n int
+	for iter.Next() {
+		pages++
+		n += len(iter.Page())
+	}
+	if err := iter.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if pages != 3 || n != MaxResults*2+1 {
+		t.Errorf("got %d issues in %d pages, want %d in 3", n, pages, MaxResults*2+1)
+	}
+}
+
+func TestAuth(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	srv.AddUser(jira.User{Name: "fred"}, "secret")
+	srv.AddUser(jira.User{Name: "ann"}, "hunter2")
+	srv.AddToken("ann", "t0ken")
+	key := srv.AddIssue("TEST", map[string]any{"summary": "hello"})
+	id := srv.AddComment(key, jira.User{Name: "fred"}, "mine")
+
+	client := srv.Client()
+	if _, err := client.Issue(key); !errors.Is(err, jira.ErrUnauthorized) {
+		t.Errorf("want ErrUnauthorized without credentials, got %v", err)
+	}
+	client.Auth = &jira.BasicAuth{Username: "fred", Password: "wrong"}
+	if _, err := client.Issue(key); !errors.Is(err, jira.ErrUnauthorized) {
+		t.Errorf("want ErrUnauthorized with bad password, got %v", err)
+	}
+	client.Auth = jira.BearerToken("t0ken")
+	me, err := client.Myself()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if me.Name != "ann" {
+		t.Errorf("authenticated as %s with ann's token", me.Name)
+	}
+	if err := client.DeleteComment(key, id); !errors.Is(err, jira.ErrUnauthorized) {
+		t.Errorf("want ErrUnauthorized deleting another's comment, got %v", err)
+	}
+	if err := client.UpdateIssue(key, map[string]any{"assignee": map[string]string{"name": "nobody"}}); err == nil {
+		t.Error("assigned issue to unknown user")
+	}
+
+	client.Auth = &jira.BasicAuth{Username: "fred", Password: "secret"}
+	if err := client.DeleteComment(key, id); err != nil {
+		t.Errorf("delete own comment: %v", err)
+	}
+}
+
+func TestCustomFields(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	id := srv.AddField("Story Points", jira.FieldSchema{Type: "number"})
+	key := srv.AddIssue("TEST", map[string]any{"summary": "hello", id: 3})
+	client := srv.Client()
+
+	reg, err := client.Fields()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f, ok := reg.Lookup("Story Points"); !ok || f.ID != id {
+		t.Errorf("lookup Story Points: got %+v, want ID %s", f, id)
+	}
+	issue, err := client.Issue(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(issue.Extra[id]); got != "3" {
+		t.Errorf("issue has %s = %s, want 3", id, got)
+	}
+	if err := client.UpdateIssue(key, map[string]any{"customfield_1": "x"}); err == nil {
+		t.Error("set unknown custom field")
+	}
+
+	fsys := &jira.FS{Client: client, CustomFields: []string{"Story Points"}}
+	b, err := fs.ReadFile(fsys, "TEST/1/issue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b = []byte(strings.Replace(string(b), "Story-Points: 3", "Story-Points: 8", 1))
+	if err := fsys.WriteFile("TEST/1/issue", b); err != nil {
+		t.Fatal(err)
+	}
+	if issue, err := client.Issue(key); err != nil || string(issue.Extra[id]) != "8" {
+		t.Errorf("story points not updated: %v, %v", issue.Extra, err)
+	}
+}
+
+func TestLinks(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	a := srv.AddIssue("TEST", map[string]any{"summary": "printer on fire"})
+	b := srv.AddIssue("TEST", map[string]any{"summary": "buy extinguisher"})
+	client := srv.Client()
+
+	types, err := client.LinkTypes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(types) != len(LinkTypes) {
+		t.Errorf("got %d link types, want %d", len(types), len(LinkTypes))
+	}
+	if err := client.Link("Blocks", b, a); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Link("Nope", a, b); err == nil {
+		t.Error("linked with unknown type")
+	}
+	if err := client.Link("Blocks", a, a); err == nil {
+		t.Error("linked issue to itself")
+	}
+	blocked, err := client.Issue(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blocked.Links) != 1 || blocked.Links[0].Relation() != "is blocked by" || blocked.Links[0].Issue.Key != b {
+		t.Fatalf("got links of %s %+v, want is blocked by %s", a, blocked.Links, b)
+	}
+	if blocked.Links[0].Issue.Summary != "buy extinguisher" {
+		t.Errorf("linked issue has summary %q", blocked.Links[0].Issue.Summary)
+	}
+	blocker, err := client.Issue(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blocker.Links) != 1 || blocker.Links[0].Relation() != "blocks" || blocker.Links[0].Issue.Key != a {
+		t.Errorf("got links of %s %+v, want blocks %s", b, blocker.Links, a)
+	}
+
+	fsys := &jira.FS{Client: client}
+	f, err := fs.ReadFile(fsys, "TEST/1/issue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := strings.Replace(string(f), "Is-Blocked-By: "+b, "Is-Blocked-By:\nRelates-To: "+b, 1)
+	if err := fsys.WriteFile("TEST/1/issue", []byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	related, err := client.Issue(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(related.Links) != 1 || related.Links[0].Relation() != "relates to" {
+		t.Errorf("got links %+v after edit, want only relates to %s", related.Links, b)
+	}
+	if err := client.Unlink(related.Links[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Unlink(related.Links[0].ID); err == nil {
+		t.Error("removed link twice")
+	}
+}
+
+func TestChangelog(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	srv.AddUser(jira.User{Name: "fred", DisplayName: "Fred Smith"}, "secret")
+	key := srv.AddIssue("TEST", map[string]any{"summary": "printer on fire", "labels": []string{"bug"}})
+	client := srv.Client()
+	client.Auth = &jira.BasicAuth{Username: "fred", Password: "secret"}
+
+	edit := map[string]any{
+		"summary":  "printer still on fire",
+		"assignee": map[string]string{"name": "fred"},
+		"labels":   []string{"bug", "urgent"},
+	}
+	if err := client.UpdateIssue(key, edit); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Transition(key, "Done"); err != nil {
+		t.Fatal(err)
+	}
+	changes, err := client.Changelog(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 2 {
+		t.Fatalf("got %d changes, want 2", len(changes))
+	}
+	want := []jira.ChangeItem{
+		{Field: "assignee", FieldType: "jira", To: "fred", ToString: "Fred Smith"},
+		{Field: "labels", FieldType: "jira", FromString: "bug", ToString: "bug urgent"},
+		{Field: "summary", FieldType: "jira", FromString: "printer on fire", ToString: "printer still on fire"},
+	}
+	if !slices.Equal(changes[0].Items, want) {
+		t.Errorf("got change items %+v, want %+v", changes[0].Items, want)
+	}
+	if changes[0].Author.Name != "fred" {
+		t.Errorf("change made by %q, want fred", changes[0].Author.Name)
+	}
+	if it := changes[1].Items; len(it) != 2 || it[0].ToString != "Done" || it[1].Field != "resolution" {
+		t.Errorf("got transition change items %+v", it)
+	}
+
+	fsys := &jira.FS{Client: client}
+	b, err := fs.ReadFile(fsys, "TEST/1/history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"assigned Fred Smith", "labeled urgent", "changed title", "changed status"} {
+		if !strings.Contains(string(b), s) {
+			t.Errorf("history missing %q:\n%s", s, b)
+		}
+	}
+}
+
+func TestAttachments(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	key := srv.AddIssue("TEST", map[string]any{"summary": "printer on fire"})
+	srv.AddAttachment(key, jira.User{Name: "fred"}, "smoke.txt", []byte("lots of smoke\n"))
+	client := srv.Client()
+
+	if _, err := client.Attach(key, "flames.txt", strings.NewReader("big flames\n")); err != nil {
+		t.Fatal(err)
+	}
+	issue, err := client.Issue(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issue.Attachments) != 2 {
+		t.Fatalf("got %d attachments, want 2", len(issue.Attachments))
+	}
+	fsys := &jira.FS{Client: client}
+	for name, want := range map[string]string{"smoke.txt": "lots of smoke\n", "flames.txt": "big flames\n"} {
+		b, err := fs.ReadFile(fsys, "TEST/1/attachments/"+name)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if string(b) != want {
+			t.Errorf("read %s: got %q, want %q", name, b, want)
+		}
+		info, err := fs.Stat(fsys, "TEST/1/attachments/"+name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Size() != int64(len(want)) || info.ModTime().IsZero() {
+			t.Errorf("stat %s: got size %d, modified %s", name, info.Size(), info.ModTime())
+		}
+	}
+	if _, err := client.Attach("TEST-99", "flames.txt", strings.NewReader("")); err == nil {
+		t.Error("attached file to unknown issue")
+	}
+}
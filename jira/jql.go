@@ -0,0 +1,1054 @@
+package jira
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Query is a parsed JQL query.
+type Query struct {
+	// Where holds the query's conditions, or nil if it has none.
+	Where Expr
+	// OrderBy lists the fields by which results are sorted.
+	OrderBy []OrderBy
+}
+
+// OrderBy is a field in a query's ORDER BY clause.
+type OrderBy struct {
+	Field string
+	Desc  bool
+}
+
+// Expr is a JQL expression: an *AndExpr, *OrExpr, *NotExpr or *Clause.
+type Expr interface {
+	String() string
+	expr()
+}
+
+// AndExpr matches issues matched by both X and Y.
+type AndExpr struct{ X, Y Expr }
+
+// OrExpr matches issues matched by either X or Y.
+type OrExpr struct{ X, Y Expr }
+
+// NotExpr matches issues not matched by X.
+type NotExpr struct{ X Expr }
+
+// Clause compares a field with one or more values,
+// as in "status = Done" or "assignee in (fred, ann)".
+type Clause struct {
+	Field string
+	// Op is the operator in lower case with single spaces:
+	// one of =, !=, <, <=, >, >=, ~, !~, in, "not in", is, "is not",
+	// was, "was not", "was in", "was not in" or changed.
+	Op string
+	// Values holds the operand. Operators taking a list,
+	// such as in, have one value per item in the list.
+	// The changed operator has no operand.
+	Values []Value
+	// Predicates holds the history predicates following
+	// was and changed, such as "after -1w" or "by fred".
+	Predicates []Predicate
+	// Pos is the byte offset of the clause in the query.
+	Pos int
+}
+
+// Value is an operand in a clause.
+type Value struct {
+	// Text is the literal value, unquoted,
+	// or the function name if Func is set.
+	Text string
+	// Quoted is set if the value was given in quotes,
+	// so that keywords such as EMPTY are taken literally.
+	Quoted bool
+	// Func is set for function calls, such as currentUser(),
+	// with Args holding their arguments.
+	Func bool
+	Args []string
+}
+
+// Predicate qualifies the history operators was and changed.
+type Predicate struct {
+	Name   string // after, before, by, during, on, from or to
+	Values []Value
+}
+
+func (*AndExpr) expr() {}
+func (*OrExpr) expr()  {}
+func (*NotExpr) expr() {}
+func (*Clause) expr()  {}
+
+// SyntaxError reports a malformed JQL query.
+type SyntaxError struct {
+	Query string
+	Pos   int // byte offset in Query
+	Msg   string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("jql: character %d: %s", e.Pos+1, e.Msg)
+}
+
+// Empty reports whether v is the keyword EMPTY or NULL.
+func (v Value) Empty() bool {
+	return !v.Quoted && !v.Func && (strings.EqualFold(v.Text, "empty") || strings.EqualFold(v.Text, "null"))
+}
+
+func (v Value) String() string {
+	if v.Func {
+		args := make([]string, len(v.Args))
+		for i, a := range v.Args {
+			args[i] = quoteJQL(a)
+		}
+		return v.Text + "(" + strings.Join(args, ", ") + ")"
+	}
+	if v.Empty() {
+		return strings.ToUpper(v.Text)
+	}
+	if v.Quoted {
+		return quote(v.Text)
+	}
+	return quoteJQL(v.Text)
+}
+
+// jqlPlain matches the words which need no quotes:
+// identifiers, numbers and custom fields such as cf[10010].
+var jqlPlain = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*|[0-9]+(\.[0-9]+)?|cf\[[0-9]+\])$`)
+
+// quoteJQL returns s quoted if needed to be read back as a single value.
+// Anything but a plain identifier or number is quoted,
+// as are the words reserved by Jira.
+func quoteJQL(s string) string {
+	if jqlPlain.MatchString(s) && !isKeyword(s) && !slices.Contains(jqlReserved, strings.ToLower(s)) {
+		return s
+	}
+	return quote(s)
+}
+
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+func valueList(values []Value) string {
+	if len(values) == 1 && values[0].Func {
+		return values[0].String()
+	}
+	s := make([]string, len(values))
+	for i, v := range values {
+		s[i] = v.String()
+	}
+	return "(" + strings.Join(s, ", ") + ")"
+}
+
+func (c *Clause) String() string {
+	var b strings.Builder
+	b.WriteString(quoteJQL(c.Field))
+	b.WriteString(" " + strings.ToUpper(c.Op))
+	switch {
+	case c.Op == "changed":
+	case strings.HasSuffix(c.Op, "in"):
+		b.WriteString(" " + valueList(c.Values))
+	case len(c.Values) > 0:
+		b.WriteString(" " + c.Values[0].String())
+	}
+	for _, p := range c.Predicates {
+		b.WriteString(" " + strings.ToUpper(p.Name) + " ")
+		if len(p.Values) == 1 {
+			b.WriteString(p.Values[0].String())
+		} else {
+			b.WriteString(valueList(p.Values))
+		}
+	}
+	return b.String()
+}
+
+func (e *AndExpr) String() string {
+	return paren(e.X, false) + " AND " + paren(e.Y, false)
+}
+
+func (e *OrExpr) String() string {
+	return e.X.String() + " OR " + e.Y.String()
+}
+
+func (e *NotExpr) String() string {
+	return "NOT " + paren(e.X, true)
+}
+
+// paren returns x as a string, in parentheses if needed
+// to bind more tightly than AND, or than NOT if not is set.
+func paren(x Expr, not bool) string {
+	switch x.(type) {
+	case *OrExpr:
+		return "(" + x.String() + ")"
+	case *AndExpr:
+		if not {
+			return "(" + x.String() + ")"
+		}
+	}
+	return x.String()
+}
+
+// String returns the query in a canonical form,
+// with keywords in upper case and only necessary parentheses.
+func (q *Query) String() string {
+	var s []string
+	if q.Where != nil {
+		s = append(s, q.Where.String())
+	}
+	if len(q.OrderBy) > 0 {
+		keys := make([]string, len(q.OrderBy))
+		for i, o := range q.OrderBy {
+			keys[i] = quoteJQL(o.Field)
+			if o.Desc {
+				keys[i] += " DESC"
+			}
+		}
+		s = append(s, "ORDER BY "+strings.Join(keys, ", "))
+	}
+	return strings.Join(s, " ")
+}
+
+// jqlSpecial holds the characters which end an unquoted word.
+const jqlSpecial = `()=,!<>~"'&|`
+
+var jqlKeywords = []string{
+	"and", "or", "not", "in", "is", "empty", "null",
+	"order", "by", "asc", "desc", "was", "changed",
+}
+
+func isKeyword(s string) bool {
+	return slices.Contains(jqlKeywords, strings.ToLower(s))
+}
+
+// jqlReserved lists the words which Jira rejects unless quoted.
+var jqlReserved = []string{
+	"a", "an", "abort", "access", "add", "after", "alias", "all", "alter", "and",
+	"any", "are", "as", "asc", "at", "audit", "avg", "before", "begin", "between",
+	"boolean", "break", "by", "byte", "catch", "cf", "char", "character", "check",
+	"checkpoint", "collate", "collation", "column", "commit", "connect", "continue",
+	"count", "create", "current", "date", "decimal", "declare", "decrement",
+	"default", "defaults", "define", "delete", "delimiter", "desc", "difference",
+	"distinct", "divide", "do", "double", "drop", "else", "empty", "encoding", "end",
+	"equals", "escape", "exclusive", "exec", "execute", "exists", "explain", "false",
+	"fetch", "file", "field", "first", "float", "for", "from", "function", "go",
+	"goto", "grant", "greater", "group", "having", "identified", "if", "immediate",
+	"in", "increment", "index", "initial", "inner", "inout", "input", "insert", "int",
+	"integer", "intersect", "intersection", "into", "is", "isempty", "isnull", "join",
+	"last", "left", "less", "like", "limit", "lock", "long", "max", "min", "minus",
+	"mode", "modify", "modulo", "more", "multiply", "next", "noaudit", "not",
+	"notin", "nowait", "null", "number", "object", "of", "on", "option", "or",
+	"order", "outer", "output", "power", "previous", "prior", "privileges",
+	"public", "raise", "raw", "remainder", "rename", "resource", "return",
+	"returns", "revoke", "right", "row", "rowid", "rownum", "rows", "select",
+	"session", "set", "share", "size", "sqrt", "start", "strict", "string",
+	"subtract", "sum", "synonym", "table", "then", "to", "trans", "transaction",
+	"trigger", "true", "uid", "union", "unique", "update", "user", "validate",
+	"values", "view", "when", "whenever", "where", "while", "with",
+}
+
+var historyPredicates = []string{"after", "before", "by", "during", "on", "from", "to"}
+
+const (
+	tokEOF = iota
+	tokWord
+	tokString
+	tokOp
+	tokPunct
+)
+
+type token struct {
+	kind int
+	text string
+	pos  int
+}
+
+// is reports whether t is the unquoted keyword kw, ignoring case.
+func (t token) is(kw string) bool {
+	return t.kind == tokWord && strings.EqualFold(t.text, kw)
+}
+
+func (t token) punct(s string) bool {
+	return t.kind == tokPunct && t.text == s
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of query"
+	case tokString:
+		return strconv.Quote(t.text)
+	}
+	return "'" + t.text + "'"
+}
+
+func lexJQL(s string) ([]token, error) {
+	var toks []token
+	for i := 0; i < len(s); {
+		c := s[i]
+		two := ""
+		if i+1 < len(s) {
+			two = s[i : i+2]
+		}
+		r, n := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case unicode.IsSpace(r):
+			i += n
+		case c == '(' || c == ')' || c == ',':
+			toks = append(toks, token{tokPunct, s[i : i+1], i})
+			i++
+		case two == "&&" || two == "||":
+			word := map[string]string{"&&": "AND", "||": "OR"}[two]
+			toks = append(toks, token{tokWord, word, i})
+			i += 2
+		case two == "!=" || two == "!~" || two == "<=" || two == ">=":
+			toks = append(toks, token{tokOp, two, i})
+			i += 2
+		case c == '!':
+			toks = append(toks, token{tokWord, "NOT", i})
+			i++
+		case c == '=' || c == '<' || c == '>' || c == '~':
+			toks = append(toks, token{tokOp, s[i : i+1], i})
+			i++
+		case c == '"' || c == '\'':
+			var b strings.Builder
+			j := i + 1
+			for ; j < len(s) && s[j] != c; j++ {
+				if s[j] == '\\' && j+1 < len(s) {
+					j++
+				}
+				b.WriteByte(s[j])
+			}
+			if j >= len(s) {
+				return nil, &SyntaxError{s, i, "unterminated quoted string"}
+			}
+			toks = append(toks, token{tokString, b.String(), i})
+			i = j + 1
+		case c == '&' || c == '|':
+			return nil, &SyntaxError{s, i, fmt.Sprintf("unexpected %q", c)}
+		default:
+			j := i
+			for j < len(s) {
+				r, n := utf8.DecodeRuneInString(s[j:])
+				if unicode.IsSpace(r) || strings.ContainsRune(jqlSpecial, r) {
+					break
+				}
+				j += n
+			}
+			toks = append(toks, token{tokWord, s[i:j], i})
+			i = j
+		}
+	}
+	return append(toks, token{tokEOF, "", len(s)}), nil
+}
+
+type jqlParser struct {
+	query string
+	toks  []token
+}
+
+func (p *jqlParser) peek() token { return p.toks[0] }
+
+func (p *jqlParser) next() token {
+	t := p.toks[0]
+	if t.kind != tokEOF {
+		p.toks = p.toks[1:]
+	}
+	return t
+}
+
+func (p *jqlParser) errorf(t token, format string, args ...any) error {
+	return &SyntaxError{p.query, t.pos, fmt.Sprintf(format, args...)}
+}
+
+func (p *jqlParser) expect(punct string) error {
+	if t := p.next(); !t.punct(punct) {
+		return p.errorf(t, "expected '%s', got %s", punct, t)
+	}
+	return nil
+}
+
+// ParseQuery parses the JQL query s.
+// Any field names are accepted, as Jira servers may define their own fields.
+// Errors in the syntax of the query are reported as a *SyntaxError.
+func ParseQuery(s string) (*Query, error) {
+	toks, err := lexJQL(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &jqlParser{query: s, toks: toks}
+	q := &Query{}
+	if p.peek().kind != tokEOF && !p.peek().is("order") {
+		if q.Where, err = p.or(); err != nil {
+			return nil, err
+		}
+	}
+	if p.peek().is("order") {
+		p.next()
+		if t := p.next(); !t.is("by") {
+			return nil, p.errorf(t, "expected BY, got %s", t)
+		}
+		for {
+			field, err := p.field()
+			if err != nil {
+				return nil, err
+			}
+			o := OrderBy{Field: field}
+			if p.peek().is("asc") {
+				p.next()
+			} else if p.peek().is("desc") {
+				p.next()
+				o.Desc = true
+			}
+			q.OrderBy = append(q.OrderBy, o)
+			if !p.peek().punct(",") {
+				break
+			}
+			p.next()
+		}
+	}
+	if t := p.next(); t.kind != tokEOF {
+		if q.Where == nil && len(q.OrderBy) == 0 {
+			return nil, p.errorf(t, "expected field name, got %s", t)
+		}
+		return nil, p.errorf(t, "expected AND, OR or ORDER BY, got %s", t)
+	}
+	return q, nil
+}
+
+func (p *jqlParser) or() (Expr, error) {
+	x, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().is("or") {
+		p.next()
+		y, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		x = &OrExpr{x, y}
+	}
+	return x, nil
+}
+
+func (p *jqlParser) and() (Expr, error) {
+	x, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().is("and") {
+		p.next()
+		y, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		x = &AndExpr{x, y}
+	}
+	return x, nil
+}
+
+func (p *jqlParser) unary() (Expr, error) {
+	if p.peek().is("not") {
+		p.next()
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &NotExpr{x}, nil
+	}
+	if p.peek().punct("(") {
+		p.next()
+		x, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return x, nil
+	}
+	return p.clause()
+}
+
+func (p *jqlParser) field() (string, error) {
+	t := p.next()
+	if t.kind == tokString || t.kind == tokWord && !isKeyword(t.text) {
+		return t.text, nil
+	}
+	return "", p.errorf(t, "expected field name, got %s", t)
+}
+
+func (p *jqlParser) clause() (Expr, error) {
+	pos := p.peek().pos
+	field, err := p.field()
+	if err != nil {
+		return nil, err
+	}
+	c := &Clause{Field: field, Pos: pos}
+	t := p.next()
+	switch {
+	case t.kind == tokOp:
+		c.Op = t.text
+	case t.is("in"):
+		c.Op = "in"
+	case t.is("not") && p.peek().is("in"):
+		p.next()
+		c.Op = "not in"
+	case t.is("is"):
+		c.Op = "is"
+		if p.peek().is("not") {
+			p.next()
+			c.Op = "is not"
+		}
+	case t.is("was"):
+		c.Op = "was"
+		if p.peek().is("not") {
+			p.next()
+			c.Op = "was not"
+		}
+		if p.peek().is("in") {
+			p.next()
+			c.Op += " in"
+		}
+	case t.is("changed"):
+		c.Op = "changed"
+	default:
+		return nil, p.errorf(t, "expected operator after %s, got %s", quoteJQL(field), t)
+	}
+
+	switch {
+	case c.Op == "changed":
+	case strings.HasSuffix(c.Op, "in"):
+		if c.Values, err = p.list(); err != nil {
+			return nil, err
+		}
+	case c.Op == "is" || c.Op == "is not":
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		if !v.Empty() {
+			return nil, p.errorf(t, "expected EMPTY after %s, got %s", strings.ToUpper(c.Op), v)
+		}
+		c.Values = []Value{v}
+	default:
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		c.Values = []Value{v}
+	}
+
+	if strings.HasPrefix(c.Op, "was") || c.Op == "changed" {
+		for {
+			t := p.peek()
+			if t.kind != tokWord || !slices.Contains(historyPredicates, strings.ToLower(t.text)) {
+				break
+			}
+			p.next()
+			pred := Predicate{Name: strings.ToLower(t.text)}
+			if p.peek().punct("(") {
+				pred.Values, err = p.list()
+			} else {
+				var v Value
+				v, err = p.value()
+				pred.Values = []Value{v}
+			}
+			if err != nil {
+				return nil, err
+			}
+			c.Predicates = append(c.Predicates, pred)
+		}
+	}
+	return c, nil
+}
+
+// list parses a parenthesised, comma-separated list of values,
+// or a function returning a list, such as membersOf(devs).
+func (p *jqlParser) list() ([]Value, error) {
+	if t := p.peek(); t.kind == tokWord && p.toks[1].punct("(") {
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		return []Value{v}, nil
+	}
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	var values []Value
+	for {
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+		t := p.next()
+		if t.punct(")") {
+			return values, nil
+		} else if !t.punct(",") {
+			return nil, p.errorf(t, "expected ',' or ')', got %s", t)
+		}
+	}
+}
+
+func (p *jqlParser) value() (Value, error) {
+	t := p.next()
+	if t.kind == tokString {
+		return Value{Text: t.text, Quoted: true}, nil
+	}
+	if t.kind != tokWord || isKeyword(t.text) && !t.is("empty") && !t.is("null") {
+		return Value{}, p.errorf(t, "expected value, got %s", t)
+	}
+	if !p.peek().punct("(") {
+		return Value{Text: t.text}, nil
+	}
+	p.next()
+	v := Value{Text: t.text, Func: true}
+	if p.peek().punct(")") {
+		p.next()
+		return v, nil
+	}
+	for {
+		a := p.next()
+		if a.kind != tokWord && a.kind != tokString {
+			return Value{}, p.errorf(a, "expected argument to %s, got %s", t.text, a)
+		}
+		v.Args = append(v.Args, a.text)
+		t := p.next()
+		if t.punct(")") {
+			return v, nil
+		} else if !t.punct(",") {
+			return Value{}, p.errorf(t, "expected ',' or ')', got %s", t)
+		}
+	}
+}
+
+// QueryEnv holds the values used by functions in JQL queries
+// when they are evaluated locally.
+type QueryEnv struct {
+	// User is the name of the user returned by currentUser().
+	User string
+	// Now is the time returned by now() and from which
+	// relative times such as -1d are measured.
+	// If zero, the current time is used.
+	Now time.Time
+}
+
+func (env *QueryEnv) now() time.Time {
+	if env == nil || env.Now.IsZero() {
+		return time.Now()
+	}
+	return env.Now
+}
+
+// Fields which may be evaluated locally, by kind.
+var (
+	jqlStringFields = []string{"project", "key", "status", "assignee", "reporter", "type",
+		"priority", "resolution", "component", "fixversion", "affectedversion", "parent"}
+	jqlTextFields = []string{"summary", "description", "comment", "text"}
+	jqlTimeFields = []string{"created", "updated", "resolved", "due"}
+)
+
+var jqlFieldAliases = map[string]string{
+	"issue":          "key",
+	"issuekey":       "key",
+	"id":             "key",
+	"issuetype":      "type",
+	"createddate":    "created",
+	"updateddate":    "updated",
+	"resolutiondate": "resolved",
+	"duedate":        "due",
+}
+
+// localField returns the canonical name of a field that may be evaluated locally,
+// or an empty string if the field is not supported.
+func localField(name string) string {
+	name = strings.ToLower(name)
+	if alias, ok := jqlFieldAliases[name]; ok {
+		name = alias
+	}
+	if slices.Contains(jqlStringFields, name) || slices.Contains(jqlTextFields, name) ||
+		slices.Contains(jqlTimeFields, name) || name == "labels" {
+		return name
+	}
+	return ""
+}
+
+// Check reports whether the query can be evaluated locally by Match and Compare.
+// Only the fields project, key, status, assignee, reporter, type, priority,
+// resolution, component, fixVersion, affectedVersion, parent, labels,
+// summary, description, comment, text, created, updated, resolved and due
+// are supported,
+// with the functions currentUser(), now(), startOfDay() and endOfDay().
+// History operators, such as was and changed, are not supported.
+func (q *Query) Check() error {
+	for _, o := range q.OrderBy {
+		// Priorities are ordered by rank, which is only known to Jira.
+		if f := localField(o.Field); f == "" || f == "priority" || slices.Contains(jqlTextFields, f) {
+			return fmt.Errorf("jql: cannot order by %s", o.Field)
+		}
+	}
+	var check func(Expr) error
+	check = func(x Expr) error {
+		switch x := x.(type) {
+		case *AndExpr:
+			if err := check(x.X); err != nil {
+				return err
+			}
+			return check(x.Y)
+		case *OrExpr:
+			if err := check(x.X); err != nil {
+				return err
+			}
+			return check(x.Y)
+		case *NotExpr:
+			return check(x.X)
+		case *Clause:
+			return x.check()
+		}
+		return nil
+	}
+	if q.Where == nil {
+		return nil
+	}
+	return check(q.Where)
+}
+
+func (c *Clause) check() error {
+	field := localField(c.Field)
+	if field == "" {
+		return fmt.Errorf("jql: field %s cannot be evaluated locally", c.Field)
+	}
+	var ops []string
+	switch {
+	case slices.Contains(jqlTextFields, field):
+		ops = []string{"~", "!~", "is", "is not"}
+	case slices.Contains(jqlTimeFields, field):
+		ops = []string{"=", "!=", "<", "<=", ">", ">=", "in", "not in", "is", "is not"}
+	default:
+		ops = []string{"=", "!=", "in", "not in", "is", "is not"}
+	}
+	if !slices.Contains(ops, c.Op) {
+		return fmt.Errorf("jql: operator %s is not supported for field %s", strings.ToUpper(c.Op), c.Field)
+	}
+	for _, v := range c.Values {
+		if v.Empty() {
+			continue
+		}
+		isTime := slices.Contains(jqlTimeFields, field)
+		if v.Func {
+			fn := strings.ToLower(v.Text)
+			switch {
+			case fn == "currentuser" && !isTime && len(v.Args) == 0:
+				continue
+			case fn == "now" && isTime && len(v.Args) == 0:
+				continue
+			case (fn == "startofday" || fn == "endofday") && isTime && len(v.Args) <= 1:
+				if len(v.Args) == 0 {
+					continue
+				}
+				if _, ok := parsePeriod(v.Args[0], true); ok {
+					continue
+				}
+			}
+			return fmt.Errorf("jql: function %s cannot be evaluated locally", v)
+		}
+		if isTime {
+			if _, ok := parseJQLTime(v.Text, time.Now()); !ok {
+				return fmt.Errorf("jql: bad date %s for field %s", v, c.Field)
+			}
+		}
+	}
+	return nil
+}
+
+// Match reports whether is satisfies the conditions of the query,
+// which should first be checked with Check.
+// Env, which may be nil, provides the values of functions such as currentUser().
+// An issue is in the project named by its key or by its project field.
+func (q *Query) Match(is *Issue, env *QueryEnv) bool {
+	return q.Where == nil || matchExpr(q.Where, is, env)
+}
+
+func matchExpr(x Expr, is *Issue, env *QueryEnv) bool {
+	switch x := x.(type) {
+	case *AndExpr:
+		return matchExpr(x.X, is, env) && matchExpr(x.Y, is, env)
+	case *OrExpr:
+		return matchExpr(x.X, is, env) || matchExpr(x.Y, is, env)
+	case *NotExpr:
+		return !matchExpr(x.X, is, env)
+	case *Clause:
+		return x.match(is, env)
+	}
+	return false
+}
+
+// strings returns the values of the clause's field in is.
+func (c *Clause) strings(is *Issue) []string {
+	var v []string
+	switch localField(c.Field) {
+	case "project":
+		proj, _, _ := strings.Cut(is.Key, "-")
+		v = []string{proj, is.Project.Key, is.Project.ID}
+	case "key":
+		v = []string{is.Key, is.ID}
+	case "status":
+		v = []string{is.Status.Name}
+	case "assignee":
+		v = []string{is.Assignee.Name}
+	case "reporter":
+		v = []string{is.Reporter.Name}
+	case "type":
+		v = []string{is.Type.Name}
+	case "priority":
+		v = []string{is.Priority.Name}
+	case "resolution":
+		v = []string{is.Resolution.Name}
+	case "component":
+		for _, cm := range is.Components {
+			v = append(v, cm.Name)
+		}
+	case "fixversion":
+		v = versionNames(is.FixVersions)
+	case "affectedversion":
+		v = versionNames(is.AffectsVersions)
+	case "parent":
+		if is.Parent != nil {
+			v = []string{is.Parent.Key, is.Parent.ID}
+		}
+	case "labels":
+		v = is.Labels
+	case "summary":
+		v = []string{is.Summary}
+	case "description":
+		v = []string{is.Description}
+	case "text":
+		v = []string{is.Summary, is.Description}
+		fallthrough
+	case "comment":
+		for _, cm := range is.Comments {
+			v = append(v, cm.Body)
+		}
+	}
+	return slices.DeleteFunc(v, func(s string) bool { return s == "" })
+}
+
+func versionNames(versions []Version) []string {
+	var names []string
+	for _, v := range versions {
+		names = append(names, v.Name, v.ID)
+	}
+	return names
+}
+
+func (c *Clause) time(is *Issue) time.Time {
+	switch localField(c.Field) {
+	case "created":
+		return is.Created
+	case "resolved":
+		return is.Resolved
+	case "due":
+		return is.Due
+	}
+	return is.Updated
+}
+
+func (c *Clause) empty(is *Issue) bool {
+	if slices.Contains(jqlTimeFields, localField(c.Field)) {
+		return c.time(is).IsZero()
+	}
+	return len(c.strings(is)) == 0
+}
+
+func (c *Clause) match(is *Issue, env *QueryEnv) bool {
+	switch c.Op {
+	case "is":
+		return c.empty(is)
+	case "is not":
+		return !c.empty(is)
+	case "~":
+		return c.contains(is)
+	case "!~":
+		return !c.empty(is) && !c.contains(is)
+	case "=", "in":
+		return slices.ContainsFunc(c.Values, func(v Value) bool { return c.equal(is, v, env) })
+	case "!=", "not in":
+		// As in Jira, issues with no value for the field never match.
+		if c.empty(is) {
+			return false
+		}
+		return !slices.ContainsFunc(c.Values, func(v Value) bool { return c.equal(is, v, env) })
+	}
+	t := c.time(is)
+	want, ok := c.Values[0].time(env)
+	if t.IsZero() || !ok {
+		return false
+	}
+	n := t.Compare(want)
+	switch c.Op {
+	case "<":
+		return n < 0
+	case "<=":
+		return n <= 0
+	case ">":
+		return n > 0
+	case ">=":
+		return n >= 0
+	}
+	return false
+}
+
+func (c *Clause) equal(is *Issue, v Value, env *QueryEnv) bool {
+	if v.Empty() {
+		return c.empty(is)
+	}
+	// Jira names the resolution of unresolved issues Unresolved.
+	if localField(c.Field) == "resolution" && !v.Quoted && strings.EqualFold(v.Text, "unresolved") {
+		return c.empty(is)
+	}
+	if slices.Contains(jqlTimeFields, localField(c.Field)) {
+		t, ok := v.time(env)
+		return ok && c.time(is).Equal(t)
+	}
+	want := v.Text
+	if v.Func {
+		if env == nil || env.User == "" {
+			return false
+		}
+		want = env.User
+	}
+	return slices.ContainsFunc(c.strings(is), func(s string) bool { return strings.EqualFold(s, want) })
+}
+
+// contains reports whether every word of the clause's value
+// appears in the field, ignoring case.
+func (c *Clause) contains(is *Issue) bool {
+	text := strings.ToLower(strings.Join(c.strings(is), "\n"))
+	for _, w := range strings.Fields(strings.ToLower(c.Values[0].Text)) {
+		if !strings.Contains(text, w) {
+			return false
+		}
+	}
+	return true
+}
+
+func (v Value) time(env *QueryEnv) (time.Time, bool) {
+	now := env.now()
+	if !v.Func {
+		return parseJQLTime(v.Text, now)
+	}
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if len(v.Args) > 0 {
+		d, ok := parsePeriod(v.Args[0], true)
+		if !ok {
+			return time.Time{}, false
+		}
+		day = day.Add(d)
+	}
+	switch strings.ToLower(v.Text) {
+	case "now":
+		return now, true
+	case "startofday":
+		return day, true
+	case "endofday":
+		return day.AddDate(0, 0, 1).Add(-time.Millisecond), true
+	}
+	return time.Time{}, false
+}
+
+var periodExp = regexp.MustCompile(`^([-+]?)((?:\d+[wdhm]\s*)+)$`)
+var periodPartExp = regexp.MustCompile(`(\d+)([wdhm])`)
+
+// parseJQLTime parses s as a date, a date and time,
+// or a period relative to now such as -2d or "-1w 2d".
+func parseJQLTime(s string, now time.Time) (time.Time, bool) {
+	for _, layout := range []string{"2006-01-02 15:04", "2006/01/02 15:04", "2006-01-02", "2006/01/02"} {
+		if t, err := time.ParseInLocation(layout, s, now.Location()); err == nil {
+			return t, true
+		}
+	}
+	d, ok := parsePeriod(s, false)
+	if !ok {
+		return time.Time{}, false
+	}
+	return now.Add(d), true
+}
+
+// parsePeriod parses a period such as -2d or "-1w 2d".
+// If days is set, a number without a unit is a number of days.
+func parsePeriod(s string, days bool) (time.Duration, bool) {
+	s = strings.TrimSpace(s)
+	if days {
+		if n, err := strconv.Atoi(s); err == nil {
+			return time.Duration(n) * 24 * time.Hour, true
+		}
+	}
+	m := periodExp.FindStringSubmatch(s)
+	if m == nil {
+		return 0, false
+	}
+	units := map[string]time.Duration{"w": 7 * 24 * time.Hour, "d": 24 * time.Hour, "h": time.Hour, "m": time.Minute}
+	var d time.Duration
+	for _, part := range periodPartExp.FindAllStringSubmatch(m[2], -1) {
+		n, _ := strconv.Atoi(part[1])
+		d += time.Duration(n) * units[part[2]]
+	}
+	if m[1] == "-" {
+		d = -d
+	}
+	return d, true
+}
+
+// Compare orders issues by the query's ORDER BY clause, then by key,
+// returning a negative number if a sorts before b,
+// a positive number if after, or zero if they are equal.
+// The query should first be checked with Check.
+func (q *Query) Compare(a, b *Issue) int {
+	for _, o := range q.OrderBy {
+		c := compareField(a, b, localField(o.Field))
+		if o.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return compareKeys(a.Key, b.Key)
+}
+
+func compareField(a, b *Issue, field string) int {
+	switch field {
+	case "key":
+		return compareKeys(a.Key, b.Key)
+	case "created":
+		return a.Created.Compare(b.Created)
+	case "updated", "resolved", "due":
+		c := &Clause{Field: field}
+		return c.time(a).Compare(c.time(b))
+	case "labels":
+		return slices.Compare(a.Labels, b.Labels)
+	}
+	c := &Clause{Field: field}
+	return strings.Compare(strings.ToLower(strings.Join(c.strings(a), ",")), strings.ToLower(strings.Join(c.strings(b), ",")))
+}
+
+// compareKeys orders issue keys by project then by number.
+func compareKeys(a, b string) int {
+	pa, na, _ := strings.Cut(a, "-")
+	pb, nb, _ := strings.Cut(b, "-")
+	if c := strings.Compare(pa, pb); c != 0 {
+		return c
+	}
+	ia, _ := strconv.Atoi(na)
+	ib, _ := strconv.Atoi(nb)
+	return cmp.Compare(ia, ib)
+}
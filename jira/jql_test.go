@@ -0,0 +1,193 @@
+package jira
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseQuery(t *testing.T) {
+	var tests = []struct {
+		query string
+		want  string
+	}{
+		{"", ""},
+		{"project = TEST", "project = TEST"},
+		{"project=TEST and status!=done", "project = TEST AND status != done"},
+		{"project = TEST && (status = Done || assignee = currentUser())", "project = TEST AND (status = Done OR assignee = currentUser())"},
+		{"not (x = 1 and y = 2) or z = 3", "NOT (x = 1 AND y = 2) OR z = 3"},
+		{"!x = 1", "NOT x = 1"},
+		{`summary ~ 'printer on fire'`, `summary ~ "printer on fire"`},
+		{`"Epic Link" = TEST-1`, `"Epic Link" = "TEST-1"`},
+		{"cf[10010] in (x, \"b c\", EMPTY)", `cf[10010] IN (x, "b c", EMPTY)`},
+		{"assignee is not empty", "assignee IS NOT EMPTY"},
+		{"labels not in (x)", "labels NOT IN (x)"},
+		{`status = "and"`, `status = "and"`},
+		{"updated >= startOfDay(-1) order by updated desc, key", `updated >= startOfDay("-1") ORDER BY updated DESC, key`},
+		{"ORDER BY created", "ORDER BY created"},
+		{"status was in (Open, Done) by fred after -1w", `status WAS IN (Open, Done) BY fred AFTER "-1w"`},
+		{"assignee changed from fred to ann during (2024-01-01, 2024-02-01)", `assignee CHANGED FROM fred TO ann DURING ("2024-01-01", "2024-02-01")`},
+		{"issue in linkedIssues(TEST-1, \"is blocked by\")", `issue IN linkedIssues("TEST-1", "is blocked by")`},
+		{`status = "Done"`, `status = "Done"`},
+		{`resolution = "Unresolved"`, `resolution = "Unresolved"`},
+		{"a = 1", `"a" = 1`},
+		{"status\u00a0=\vvoilà", "status = \"voilà\""},
+		{"reporter = fred@example.com", `reporter = "fred@example.com"`},
+	}
+	for _, tt := range tests {
+		q, err := ParseQuery(tt.query)
+		if err != nil {
+			t.Errorf("parse %q: %v", tt.query, err)
+			continue
+		}
+		if got := q.String(); got != tt.want {
+			t.Errorf("parse %q: got %q, want %q", tt.query, got, tt.want)
+		}
+		// The canonical form parses to itself.
+		q2, err := ParseQuery(q.String())
+		if err != nil {
+			t.Errorf("reparse %q: %v", q.String(), err)
+		} else if q2.String() != q.String() {
+			t.Errorf("reparse %q: got %q", q.String(), q2.String())
+		}
+	}
+}
+
+// TestQuoteRoundTrip checks that values holding
+// reserved characters, words and spaces are read back unchanged
+// from the string form of a query.
+func TestQuoteRoundTrip(t *testing.T) {
+	values := []string{
+		"fred@example.com", "a*b", "a/b", "1+1", "TEST-1", "x;y", "why?", "100%",
+		"-1w", "2024-01-01", "a.b", "cf[1", "$5", "#1", "^x", "[x]", "{x}",
+		"two words", "tab\there", "line\nbreak", "nbsp\u00a0here", "em\u2003space",
+		"voilà", "日本", `say "hi"`, `back\slash`, "it's", "a,b", "(x)", "x=y", "!x", "~x", "&", "|",
+		"and", "OR", "empty", "Null", "order", "select", "user", "cf", "a",
+		"1", "1.5", "x_1", "",
+	}
+	for _, v := range values {
+		// The field name and function argument are quoted only if needed.
+		q := &Query{Where: &Clause{Field: v, Op: "in", Values: []Value{
+			{Text: v, Quoted: true},
+			{Text: "f", Func: true, Args: []string{v}},
+		}}}
+		s := q.String()
+		q2, err := ParseQuery(s)
+		if err != nil {
+			t.Errorf("parse %q from value %q: %v", s, v, err)
+			continue
+		}
+		c, ok := q2.Where.(*Clause)
+		if !ok || len(c.Values) != 2 || len(c.Values[1].Args) != 1 {
+			t.Errorf("parse %q from value %q: got %#v", s, v, q2.Where)
+			continue
+		}
+		if c.Field != v || c.Values[0].Text != v || !c.Values[0].Quoted || c.Values[1].Args[0] != v {
+			t.Errorf("value %q: %q parsed as field %q, values %+v", v, s, c.Field, c.Values)
+		}
+		if s2 := q2.String(); s2 != s {
+			t.Errorf("value %q: %q reparsed as %q", v, s, s2)
+		}
+	}
+}
+
+func TestQuerySyntaxError(t *testing.T) {
+	var tests = []struct {
+		query string
+		pos   int
+	}{
+		{"project =", 9},
+		{"project = TEST and", 18},
+		{"project = TEST status = Done", 15},
+		{"(project = TEST", 15},
+		{"project TEST", 8},
+		{`summary ~ "unterminated`, 10},
+		{"assignee is fred", 9},
+		{"status in Done", 10},
+		{"= TEST", 0},
+		{"project = TEST order key", 21},
+	}
+	for _, tt := range tests {
+		_, err := ParseQuery(tt.query)
+		var serr *SyntaxError
+		if !errors.As(err, &serr) {
+			t.Errorf("parse %q: want *SyntaxError, got %v", tt.query, err)
+			continue
+		}
+		if serr.Pos != tt.pos {
+			t.Errorf("parse %q: error at %d, want %d: %v", tt.query, serr.Pos, tt.pos, err)
+		}
+	}
+}
+
+func TestQueryMatch(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	issues := []Issue{
+		{Key: "TEST-1", Status: Status{"Open"}, Assignee: User{Name: "fred"}, Labels: []string{"bug"}, Summary: "Printer on fire", Updated: now.Add(-48 * time.Hour)},
+		{Key: "TEST-2", Status: Status{"Done"}, Resolution: Resolution{Name: "Fixed"}, Resolved: now.Add(-time.Hour), Priority: Priority{Name: "High"}, FixVersions: []Version{{ID: "7", Name: "1.0"}}, Summary: "Out of paper", Updated: now.Add(-time.Hour), Comments: []Comment{{Body: "Refilled the tray"}}},
+		{Key: "WEB-1", Project: Project{Key: "WEB", ID: "10001"}, Status: Status{"Open"}, Assignee: User{Name: "ann"}, Updated: now},
+	}
+	env := &QueryEnv{User: "fred", Now: now}
+	var tests = []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"TEST-1", "TEST-2", "WEB-1"}},
+		{"project = test", []string{"TEST-1", "TEST-2"}},
+		{"project = 10001", []string{"WEB-1"}},
+		{"status = open and assignee = currentUser()", []string{"TEST-1"}},
+		{"assignee != fred", []string{"WEB-1"}},
+		{"assignee is empty", []string{"TEST-2"}},
+		{"assignee in (EMPTY, ann)", []string{"TEST-2", "WEB-1"}},
+		{"labels = bug", []string{"TEST-1"}},
+		{"key not in (TEST-1, TEST-2)", []string{"WEB-1"}},
+		{"updated < -1d", []string{"TEST-1"}},
+		{"updated >= startOfDay()", []string{"TEST-2", "WEB-1"}},
+		{"updated > 2024-02-29", []string{"TEST-2", "WEB-1"}},
+		{`summary ~ "fire printer"`, []string{"TEST-1"}},
+		{"text ~ tray", []string{"TEST-2"}},
+		{"not status = Done", []string{"TEST-1", "WEB-1"}},
+		{"resolution = Unresolved", []string{"TEST-1", "WEB-1"}},
+		{"resolution != unresolved and priority = high", []string{"TEST-2"}},
+		{"fixVersion in (1.0, 2.0) or fixVersion = 7", []string{"TEST-2"}},
+		{"resolutiondate >= -1d and due is empty", []string{"TEST-2"}},
+	}
+	for _, tt := range tests {
+		q, err := ParseQuery(tt.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := q.Check(); err != nil {
+			t.Errorf("check %q: %v", tt.query, err)
+			continue
+		}
+		var got []string
+		for i := range issues {
+			if q.Match(&issues[i], env) {
+				got = append(got, issues[i].Key)
+			}
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%q matched %v, want %v", tt.query, got, tt.want)
+		}
+	}
+
+	q, err := ParseQuery("order by updated desc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Compare(&issues[0], &issues[2]) <= 0 {
+		t.Error("older issue sorted first in descending order")
+	}
+
+	for _, s := range []string{"colour = red", "status ~ Done", "updated > yesterday", "status was Open", "assignee in membersOf(devs)", "order by summary", "order by priority"} {
+		q, err := ParseQuery(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := q.Check(); err == nil {
+			t.Errorf("check %q: no error", s)
+		}
+	}
+}
@@ -0,0 +1,474 @@
+package jira
+
+import (
+	"fmt"
+	"go/doc/comment"
+	"regexp"
+	"strings"
+)
+
+// https://jira.atlassian.com/secure/WikiRendererHelpAction.jspa?section=all
+
+var (
+	linkDef    = regexp.MustCompile(`^\[([^\]]+)\]:\s*(\S+)\s*$`)
+	listMarker = regexp.MustCompile(`^\s+([-*+•]|[0-9]+[.)])\s+(.*)$`)
+	tableRule  = regexp.MustCompile(`^\|(\s*:?-+:?\s*\|)+\s*$`)
+	mdBold     = regexp.MustCompile(`\*\*(.+?)\*\*`)
+	mdCode     = regexp.MustCompile("`([^`]+)`")
+)
+
+// ToJTF converts text in the format of Go doc comments
+// (see package [go/doc/comment]) to Jira text formatting.
+// Some Markdown-like extensions are also recognised:
+// fenced code blocks with an optional language,
+// tables with rows delimited by "|",
+// block quotes prefixed by ">",
+// nested lists, **bold** and `monospace` text.
+func ToJTF(content string) string {
+	lines := strings.Split(strings.ReplaceAll(content, "\r", ""), "\n")
+	var defs, rest []string
+	for _, line := range lines {
+		if linkDef.MatchString(line) {
+			defs = append(defs, line)
+		} else {
+			rest = append(rest, line)
+		}
+	}
+	c := &jtfConverter{defs: strings.Join(defs, "\n")}
+	return c.convert(rest)
+}
+
+// jtfConverter converts blocks of text to Jira text formatting.
+// Blocks not supported by package go/doc/comment are handled directly;
+// the rest are parsed with the link definitions in defs.
+type jtfConverter struct {
+	defs string
+}
+
+func (c *jtfConverter) convert(lines []string) string {
+	var blocks, doc []string
+	flush := func() {
+		if strings.TrimSpace(strings.Join(doc, "")) != "" {
+			blocks = append(blocks, c.doc(strings.Join(doc, "\n")))
+		}
+		doc = nil
+	}
+	var inCode bool
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if inCode {
+			if isIndented(line) || strings.TrimSpace(line) == "" {
+				doc = append(doc, line)
+				continue
+			}
+			inCode = false
+		}
+		switch {
+		case strings.HasPrefix(line, "```"):
+			flush()
+			lang := strings.TrimSpace(strings.TrimPrefix(line, "```"))
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(lines[i], "```"); i++ {
+				code = append(code, lines[i])
+			}
+			blocks = append(blocks, codeJTF(lang, code))
+		case strings.HasPrefix(line, "|"):
+			flush()
+			j := i
+			for j < len(lines) && strings.HasPrefix(lines[j], "|") {
+				j++
+			}
+			blocks = append(blocks, c.table(lines[i:j]))
+			i = j - 1
+		case strings.HasPrefix(line, ">"):
+			flush()
+			var quoted []string
+			for ; i < len(lines) && strings.HasPrefix(lines[i], ">"); i++ {
+				l := strings.TrimPrefix(lines[i], ">")
+				quoted = append(quoted, strings.TrimPrefix(l, " "))
+			}
+			i--
+			blocks = append(blocks, c.quote(quoted))
+		case listMarker.MatchString(line):
+			flush()
+			j := listEnd(lines, i)
+			blocks = append(blocks, c.list(lines[i:j]))
+			i = j - 1
+		case isIndented(line):
+			// leave code blocks to package comment.
+			inCode = true
+			doc = append(doc, line)
+		default:
+			doc = append(doc, line)
+		}
+	}
+	flush()
+	return strings.Join(blocks, "\n\n")
+}
+
+func isIndented(line string) bool {
+	return strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")
+}
+
+// listEnd returns the index of the line after the end of the list starting at lines[i].
+// Like package comment, a list is a span of indented or blank lines.
+func listEnd(lines []string, i int) int {
+	for i < len(lines) {
+		if isIndented(lines[i]) && strings.TrimSpace(lines[i]) != "" {
+			i++
+			continue
+		}
+		// include blank lines only if the list continues after them.
+		j := i
+		for j < len(lines) && strings.TrimSpace(lines[j]) == "" {
+			j++
+		}
+		if j == i || j == len(lines) || !isIndented(lines[j]) {
+			break
+		}
+		i = j
+	}
+	return i
+}
+
+func (c *jtfConverter) doc(text string) string {
+	var p comment.Parser
+	doc := p.Parse(text + "\n\n" + c.defs)
+	var blocks []string
+	for _, block := range doc.Content {
+		switch v := block.(type) {
+		case *comment.Heading:
+			blocks = append(blocks, "h3. "+render(v.Text))
+		case *comment.Paragraph:
+			blocks = append(blocks, render(v.Text))
+		case *comment.Code:
+			blocks = append(blocks, fmt.Sprintf("{noformat}%s{noformat}", v.Text))
+		case *comment.List:
+			blocks = append(blocks, strings.TrimSpace(renderList(v)))
+		}
+	}
+	return strings.Join(blocks, "\n\n")
+}
+
+// inline converts a single line of text, such as a list item or table cell.
+func (c *jtfConverter) inline(text string) string {
+	var p comment.Parser
+	doc := p.Parse(text + "\n\n" + c.defs)
+	var s []string
+	for _, block := range doc.Content {
+		switch v := block.(type) {
+		case *comment.Heading:
+			s = append(s, "# "+render(v.Text))
+		case *comment.Paragraph:
+			s = append(s, render(v.Text))
+		}
+	}
+	return strings.Join(s, " ")
+}
+
+// list converts a list, nested by indentation, to Jira's list syntax
+// in which an item's depth is its number of markers, such as "**" or "#*".
+func (c *jtfConverter) list(lines []string) string {
+	type item struct {
+		indent int
+		marker string
+		text   string
+	}
+	var items []item
+	for _, line := range lines {
+		if m := listMarker.FindStringSubmatch(line); m != nil {
+			marker := "*"
+			if m[1][0] >= '0' && m[1][0] <= '9' {
+				marker = "#"
+			}
+			indent := len(strings.ReplaceAll(line[:len(line)-len(strings.TrimLeft(line, " \t"))], "\t", "    "))
+			items = append(items, item{indent, marker, m[2]})
+		} else if s := strings.TrimSpace(line); s != "" && len(items) > 0 {
+			items[len(items)-1].text += " " + s
+		}
+	}
+
+	buf := &strings.Builder{}
+	var indents []int
+	var markers []string
+	for _, it := range items {
+		for len(indents) > 0 && indents[len(indents)-1] > it.indent {
+			indents = indents[:len(indents)-1]
+			markers = markers[:len(markers)-1]
+		}
+		if len(indents) == 0 || indents[len(indents)-1] < it.indent {
+			indents = append(indents, it.indent)
+			markers = append(markers, it.marker)
+		}
+		markers[len(markers)-1] = it.marker
+		fmt.Fprintln(buf, strings.Join(markers, ""), c.inline(it.text))
+	}
+	return strings.TrimSpace(buf.String())
+}
+
+// table converts rows of cells delimited by "|".
+// A row followed by a rule such as "|---|---|" is a header row,
+// as is a row already in Jira's header syntax "||a||b||".
+func (c *jtfConverter) table(lines []string) string {
+	var rows [][]string
+	var header []bool
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if tableRule.MatchString(line) {
+			if len(header) > 0 {
+				header[len(header)-1] = true
+			}
+			continue
+		}
+		sep := "|"
+		if strings.HasPrefix(line, "||") {
+			sep = "||"
+		}
+		line = strings.TrimSuffix(strings.TrimPrefix(line, sep), sep)
+		cells := strings.Split(line, sep)
+		for i := range cells {
+			cells[i] = c.inline(strings.TrimSpace(cells[i]))
+		}
+		rows = append(rows, cells)
+		header = append(header, sep == "||")
+	}
+	buf := &strings.Builder{}
+	for i, cells := range rows {
+		sep := "|"
+		if header[i] {
+			sep = "||"
+		}
+		fmt.Fprintf(buf, "%s%s%s\n", sep, strings.Join(cells, sep), sep)
+	}
+	return strings.TrimSpace(buf.String())
+}
+
+// quote converts the lines of a block quote, stripped of their quote prefix.
+func (c *jtfConverter) quote(lines []string) string {
+	inner := c.convert(lines)
+	if !strings.Contains(inner, "\n") {
+		return "{quote}" + inner + "{quote}"
+	}
+	return "{quote}\n" + inner + "\n{quote}"
+}
+
+func codeJTF(lang string, lines []string) string {
+	tag := "{code}"
+	if lang != "" {
+		tag = "{code:" + lang + "}"
+	}
+	return tag + "\n" + strings.Join(lines, "\n") + "\n{code}"
+}
+
+func renderList(list *comment.List) string {
+	buf := &strings.Builder{}
+	for _, it := range list.Items {
+		prefix := "*"
+		if it.Number != "" {
+			prefix = "#"
+		}
+		for _, block := range it.Content {
+			// the block is known to be a paragraph
+			s := render(block.(*comment.Paragraph).Text)
+			fmt.Fprintln(buf, prefix, s)
+		}
+	}
+	return buf.String()
+}
+
+func render(text []comment.Text) string {
+	buf := &strings.Builder{}
+	for _, txt := range text {
+		switch v := txt.(type) {
+		case comment.Plain:
+			s := strings.ReplaceAll(string(v), "\n", " ")
+			s = mdCode.ReplaceAllString(s, "{{$1}}")
+			s = mdBold.ReplaceAllString(s, "*$1*")
+			buf.WriteString(s)
+		case comment.Italic:
+			fmt.Fprintf(buf, "_%s_", v)
+		case *comment.Link:
+			if v.Auto {
+				fmt.Fprintf(buf, "[%s]", v.URL)
+			} else {
+				title := render(v.Text)
+				fmt.Fprintf(buf, "[%s|%s]", title, v.URL)
+			}
+		case *comment.DocLink:
+			// we're not actually printing godoc, so treat
+			// any accidental DocLink as plain text.
+			buf.WriteString(render(v.Text))
+		default:
+			fmt.Fprintf(buf, "%v", v)
+		}
+	}
+	return buf.String()
+}
+
+var (
+	jtfHeading   = regexp.MustCompile(`^h[1-6]\.\s+(.*)$`)
+	jtfListItem  = regexp.MustCompile(`^([*#-]+)\s+(.*)$`)
+	jtfBlockOpen = regexp.MustCompile(`^\{(noformat|code|quote)([:|][^}]*)?\}`)
+	jtfLink      = regexp.MustCompile(`\[([^\[\]|]*)\|([^\[\]]+)\]`)
+	jtfMention   = regexp.MustCompile(`\[~([^\[\]]+)\]`)
+	jtfAutoLink  = regexp.MustCompile(`\[((?:https?|ftp|mailto):[^\[\]]+)\]`)
+	jtfMonospace = regexp.MustCompile(`\{\{(.+?)\}\}`)
+	jtfColor     = regexp.MustCompile(`\{color(?::[^}]*)?\}`)
+)
+
+// FromJTF converts text in Jira text formatting to plain text
+// in the format of Go doc comments, suitable for reading and
+// for conversion back with ToJTF.
+// Headings, preformatted blocks, quotes, lists and links are converted
+// to their Go doc comment equivalents;
+// code blocks, tables and monospace text to the extensions accepted by ToJTF.
+// Other markup is left as-is.
+func FromJTF(jtf string) string {
+	jtf = strings.ReplaceAll(jtf, "\r", "")
+	lines := strings.Split(jtf, "\n")
+	buf := &strings.Builder{}
+	var links []string
+	linkdefs := make(map[string]string)
+	inline := func(s string) string {
+		s = jtfMention.ReplaceAllString(s, "@$1")
+		s = jtfLink.ReplaceAllStringFunc(s, func(m string) string {
+			sub := jtfLink.FindStringSubmatch(m)
+			text, u := sub[1], sub[2]
+			if text == "" || text == u {
+				return u
+			}
+			if _, ok := linkdefs[text]; !ok {
+				links = append(links, text)
+				linkdefs[text] = u
+			}
+			return "[" + text + "]"
+		})
+		s = jtfAutoLink.ReplaceAllString(s, "$1")
+		s = jtfMonospace.ReplaceAllString(s, "`$1`")
+		s = jtfColor.ReplaceAllString(s, "")
+		return s
+	}
+	// blank ensures the next block is separated from the previous one.
+	blank := func() {
+		if buf.Len() > 0 && !strings.HasSuffix(buf.String(), "\n\n") {
+			buf.WriteString("\n")
+		}
+	}
+
+	var numbers []int // list item numbers by depth
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if m := jtfListItem.FindStringSubmatch(line); m != nil {
+			depth := len(m[1])
+			if numbers == nil {
+				blank()
+			}
+			for len(numbers) < depth {
+				numbers = append(numbers, 0)
+			}
+			numbers = numbers[:depth]
+			indent := strings.Repeat("  ", depth)
+			if strings.HasSuffix(m[1], "#") {
+				numbers[depth-1]++
+				fmt.Fprintf(buf, "%s%d. %s\n", indent, numbers[depth-1], inline(m[2]))
+			} else {
+				fmt.Fprintf(buf, "%s- %s\n", indent, inline(m[2]))
+			}
+			continue
+		}
+		if numbers != nil {
+			numbers = nil
+			buf.WriteString("\n")
+		}
+
+		if line == "" {
+			blank()
+			continue
+		}
+		if m := jtfHeading.FindStringSubmatch(line); m != nil {
+			blank()
+			fmt.Fprintf(buf, "# %s\n\n", inline(m[1]))
+			continue
+		}
+		if strings.HasPrefix(line, "bq. ") {
+			fmt.Fprintf(buf, "> %s\n", inline(strings.TrimPrefix(line, "bq. ")))
+			continue
+		}
+		if m := jtfBlockOpen.FindStringSubmatch(line); m != nil {
+			closing := "{" + m[1] + "}"
+			text := strings.TrimPrefix(line, m[0])
+			// consume lines until the block is closed.
+			for !strings.Contains(text, closing) && i+1 < len(lines) {
+				i++
+				text += "\n" + lines[i]
+			}
+			text, after, _ := strings.Cut(text, closing)
+			text = strings.Trim(text, "\n")
+			blank()
+			switch m[1] {
+			case "quote":
+				for _, l := range strings.Split(FromJTF(text), "\n") {
+					if l == "" {
+						buf.WriteString(">\n")
+					} else {
+						fmt.Fprintf(buf, "> %s\n", l)
+					}
+				}
+			case "code":
+				fmt.Fprintf(buf, "```%s\n%s\n```\n", codeLang(m[2]), text)
+			default:
+				for _, l := range strings.Split(text, "\n") {
+					if l == "" {
+						buf.WriteString("\n")
+					} else {
+						fmt.Fprintf(buf, "\t%s\n", l)
+					}
+				}
+			}
+			buf.WriteString("\n")
+			if after = strings.TrimSpace(after); after != "" {
+				fmt.Fprintln(buf, inline(after))
+			}
+			continue
+		}
+		if strings.HasPrefix(line, "|") {
+			header := strings.HasPrefix(line, "||")
+			sep := "|"
+			if header {
+				sep = "||"
+			}
+			line = strings.TrimSuffix(strings.TrimPrefix(inline(line), sep), sep)
+			cells := strings.Split(line, sep)
+			for j := range cells {
+				cells[j] = strings.TrimSpace(cells[j])
+			}
+			fmt.Fprintf(buf, "| %s |\n", strings.Join(cells, " | "))
+			if header {
+				fmt.Fprintf(buf, "|%s\n", strings.Repeat(" --- |", len(cells)))
+			}
+			continue
+		}
+		fmt.Fprintln(buf, inline(line))
+	}
+
+	if len(links) > 0 {
+		blank()
+		for _, text := range links {
+			fmt.Fprintf(buf, "[%s]: %s\n", text, linkdefs[text])
+		}
+	}
+	// trim only newlines to preserve indentation of a leading block.
+	return strings.Trim(buf.String(), "\n")
+}
+
+// codeLang returns the language from the parameters of a code macro,
+// such as ":java" or ":title=Example.java|borderStyle=solid".
+func codeLang(params string) string {
+	params = strings.TrimPrefix(params, ":")
+	for _, p := range strings.Split(params, "|") {
+		if p != "" && !strings.Contains(p, "=") {
+			return p
+		}
+	}
+	return ""
+}
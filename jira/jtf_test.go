@@ -0,0 +1,86 @@
+package jira
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestJTF converts each Go doc comment file in testdata/jtf
+// and compares the result against the corresponding .jtf file.
+func TestJTF(t *testing.T) {
+	names, err := filepath.Glob("testdata/jtf/*.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) == 0 {
+		t.Fatal("no test files")
+	}
+	for _, name := range names {
+		t.Run(filepath.Base(name), func(t *testing.T) {
+			b, err := os.ReadFile(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := ToJTF(string(b))
+			b, err = os.ReadFile(strings.TrimSuffix(name, ".md") + ".jtf")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := string(b); got != want {
+				t.Errorf("unexpected content in rendered comment")
+				t.Logf("got:\n%s", got)
+				t.Logf("want:\n%s", want)
+			}
+		})
+	}
+}
+
+func TestFromJTF(t *testing.T) {
+	var tests = []struct {
+		name string
+		jtf  string
+		want string
+	}{
+		{"heading", "h2. Overview\nSome text.", "# Overview\n\nSome text."},
+		{"link", "See [the docs|https://example.com].", "See [the docs].\n\n[the docs]: https://example.com"},
+		{"autolink", "See [https://example.com].", "See https://example.com."},
+		{"mention", "Thanks [~fred]!", "Thanks @fred!"},
+		{"monospace", "Run {{go test}} first.", "Run `go test` first."},
+		{"noformat", "{noformat}\nint x = 1;\n{noformat}", "\tint x = 1;"},
+		{"code", "{code:java}\nint x = 1;\n{code}", "```java\nint x = 1;\n```"},
+		{"code title", "{code:title=A.java|borderStyle=solid}\nclass A {}\n{code}", "```\nclass A {}\n```"},
+		{"table", "||a||b||\n|1|2|", "| a | b |\n| --- | --- |\n| 1 | 2 |"},
+		{"quote", "bq. Hello", "> Hello"},
+		{"numbered", "# one\n# two\n## two.a", "  1. one\n  2. two\n    1. two.a"},
+		{"bullets", "Shopping:\n* eggs\n* milk", "Shopping:\n\n  - eggs\n  - milk"},
+		{"carriage returns", "line one\r\nline two\r\n", "line one\nline two"},
+	}
+	for _, tt := range tests {
+		got := FromJTF(tt.jtf)
+		if got != tt.want {
+			t.Errorf("%s: FromJTF(%q) = %q, want %q", tt.name, tt.jtf, got, tt.want)
+		}
+	}
+}
+
+func TestJTFRoundTrip(t *testing.T) {
+	names, err := filepath.Glob("testdata/jtf/*.jtf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range names {
+		b, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := string(b)
+		got := ToJTF(FromJTF(want))
+		if got != want {
+			t.Errorf("%s: round trip changed text", name)
+			t.Logf("got:\n%s", got)
+			t.Logf("want:\n%s", want)
+		}
+	}
+}
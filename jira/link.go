@@ -0,0 +1,200 @@
+package jira
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"net/textproto"
+	"path"
+	"slices"
+	"strings"
+)
+
+// LinkTypes returns the types of links which may be made between issues.
+func (c *Client) LinkTypes() ([]LinkType, error) {
+	return c.LinkTypesContext(context.Background())
+}
+
+// LinkTypesContext is like LinkTypes, with requests made using ctx.
+func (c *Client) LinkTypesContext(ctx context.Context) ([]LinkType, error) {
+	u := *c.APIRoot
+	u.Path = path.Join(u.Path, "issueLinkType")
+	b, err := c.getJSON(ctx, u.String())
+	if err != nil {
+		return nil, err
+	}
+	var v struct {
+		IssueLinkTypes []LinkType
+	}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return nil, fmt.Errorf("decode link types: %w", err)
+	}
+	return v.IssueLinkTypes, nil
+}
+
+// Link links the issue from to the issue to with the named link type,
+// such that the outward description of the type relates from to to.
+// For example, with the type named "Blocks":
+//
+//	client.Link("Blocks", "TEST-1", "TEST-4")
+//
+// records that TEST-1 blocks TEST-4, and that TEST-4 is blocked by TEST-1.
+func (c *Client) Link(linkType, from, to string) error {
+	return c.LinkContext(context.Background(), linkType, from, to)
+}
+
+// LinkContext is like Link, with requests made using ctx.
+func (c *Client) LinkContext(ctx context.Context, linkType, from, to string) error {
+	// Jira names the issue at the outward end of the link
+	// the inward issue, and vice versa.
+	body, err := json.Marshal(map[string]any{
+		"type":         map[string]string{"name": linkType},
+		"inwardIssue":  map[string]string{"key": from},
+		"outwardIssue": map[string]string{"key": to},
+	})
+	if err != nil {
+		return fmt.Errorf("to json: %w", err)
+	}
+	u := *c.APIRoot
+	u.Path = path.Join(u.Path, "issueLink")
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Add("Content-Type", "application/json")
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return responseError(resp)
+	}
+	return nil
+}
+
+// Unlink removes the link with the given ID, as in Link.ID.
+func (c *Client) Unlink(id string) error {
+	return c.UnlinkContext(context.Background(), id)
+}
+
+// UnlinkContext is like Unlink, with requests made using ctx.
+func (c *Client) UnlinkContext(ctx context.Context, id string) error {
+	u := *c.APIRoot
+	u.Path = path.Join(u.Path, "issueLink", id)
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return responseError(resp)
+	}
+	return nil
+}
+
+// linkEdit is a link to add to or remove from an issue.
+type linkEdit struct {
+	typ     LinkType
+	outward bool
+	key     string // the linked issue
+	id      string // of an existing link, to remove
+}
+
+// linkChanges compares the link headers in header against the links of old,
+// as printed by printLinks, and returns the links to add and remove.
+// Types holds the link types known to Jira, used to find
+// the type of headers not already present in old.
+// An empty header removes every link of its relation;
+// a missing header leaves them unchanged.
+func linkChanges(old *Issue, header mail.Header, types []LinkType) (add, remove []linkEdit, err error) {
+	// Each relation, outward then inward, by header name.
+	type relation struct {
+		typ     LinkType
+		outward bool
+	}
+	relations := make(map[string]relation)
+	for _, t := range types {
+		for _, outward := range []bool{true, false} {
+			l := Link{Type: t, Outward: outward}
+			h := linkHeader(l.Relation())
+			if h == "" {
+				continue
+			}
+			k := textproto.CanonicalMIMEHeaderKey(h)
+			if _, ok := relations[k]; !ok {
+				relations[k] = relation{t, outward}
+			}
+		}
+	}
+	for _, l := range old.Links {
+		if h := linkHeader(l.Relation()); h != "" {
+			relations[textproto.CanonicalMIMEHeaderKey(h)] = relation{l.Type, l.Outward}
+		}
+	}
+
+	want := make(map[string][]string)
+	for k, v := range header {
+		if _, ok := relations[k]; !ok {
+			continue
+		}
+		for _, s := range v {
+			want[k] = append(want[k], strings.FieldsFunc(s, func(r rune) bool {
+				return r == ',' || r == ' ' || r == '\t'
+			})...)
+		}
+	}
+
+	for _, l := range old.Links {
+		k := textproto.CanonicalMIMEHeaderKey(linkHeader(l.Relation()))
+		if _, ok := header[k]; !ok {
+			continue
+		}
+		if !slices.Contains(want[k], l.Issue.Key) {
+			remove = append(remove, linkEdit{typ: l.Type, outward: l.Outward, key: l.Issue.Key, id: l.ID})
+			continue
+		}
+		want[k] = slices.DeleteFunc(want[k], func(key string) bool { return key == l.Issue.Key })
+	}
+	// Sort for a predictable order of requests.
+	names := make([]string, 0, len(want))
+	for k := range want {
+		names = append(names, k)
+	}
+	slices.Sort(names)
+	for _, k := range names {
+		keys := want[k]
+		slices.Sort(keys)
+		for _, key := range slices.Compact(keys) {
+			if key == old.Key {
+				return nil, nil, fmt.Errorf("%s: cannot link %s to itself", k, key)
+			}
+			r := relations[k]
+			add = append(add, linkEdit{typ: r.typ, outward: r.outward, key: key})
+		}
+	}
+	return add, remove, nil
+}
+
+// newHeaders reports whether header holds any header
+// missing from the issue file of old, as printed by printIssue with fields.
+// Such a header may name a relation that old has no links of.
+func newHeaders(old *Issue, header mail.Header, fields []Field) bool {
+	msg, err := mail.ReadMessage(strings.NewReader(printIssue(old, true, fields)))
+	if err != nil {
+		return true
+	}
+	for k := range header {
+		if _, ok := msg.Header[k]; !ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,367 @@
+package jira
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// SyncProject copies the named project and all of its issues
+// from Jira into the mirror in dir, creating dir if needed.
+// Only issues updated since they were last mirrored are fetched in full.
+// Issues of the project which are no longer in Jira are removed from the mirror.
+func SyncProject(ctx context.Context, client *Client, dir, project string) error {
+	s := &syncer{ctx: ctx, client: client, dir: dir}
+	if err := s.fields(); err != nil {
+		return err
+	}
+	seen, err := s.issues(fmt.Sprintf("project = %q", project))
+	if err != nil {
+		return err
+	}
+	if err := s.project(project); err != nil {
+		return err
+	}
+	dents, err := os.ReadDir(filepath.Join(dir, "issue"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	for _, d := range dents {
+		key := d.Name()
+		if !strings.HasPrefix(key, project+"-") || seen[key] {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, "issue", key)); err != nil {
+			return fmt.Errorf("remove deleted issue: %w", err)
+		}
+	}
+	return nil
+}
+
+// SyncQuery copies the issues matching the JQL query, and their projects,
+// from Jira into the mirror in dir, creating dir if needed.
+// Only issues updated since they were last mirrored are fetched in full.
+func SyncQuery(ctx context.Context, client *Client, dir, query string) error {
+	s := &syncer{ctx: ctx, client: client, dir: dir}
+	if err := s.fields(); err != nil {
+		return err
+	}
+	_, err := s.issues(query)
+	return err
+}
+
+type syncer struct {
+	ctx    context.Context
+	client *Client
+	dir    string
+	// projects holds the keys of projects already mirrored.
+	projects map[string]bool
+}
+
+// issues mirrors the issues matching query
+// and returns the set of their keys.
+func (s *syncer) issues(query string) (map[string]bool, error) {
+	seen := make(map[string]bool)
+	iter := s.client.SearchContext(s.ctx, query)
+	for iter.Next() {
+		for _, is := range iter.Page() {
+			seen[is.Key] = true
+			if err := s.issue(&is); err != nil {
+				return nil, fmt.Errorf("mirror %s: %w", is.Key, err)
+			}
+			// Keys are prefixed with their project's key,
+			// as in the FS, even for issues moved between projects.
+			proj, _, _ := strings.Cut(is.Key, "-")
+			if err := s.project(proj); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("search %q: %w", query, err)
+	}
+	return seen, nil
+}
+
+// issue mirrors the issue found by a search, and its comments,
+// unless the mirrored copy is as recent.
+func (s *syncer) issue(found *Issue) error {
+	name := filepath.Join(s.dir, "issue", found.Key)
+	if b, err := os.ReadFile(name); err == nil {
+		var old Issue
+		if json.Unmarshal(b, &old) == nil && old.Updated.Equal(found.Updated) {
+			return nil
+		}
+	}
+	raw, err := s.client.issueJSON(s.ctx, found.Key, "")
+	if err != nil {
+		return err
+	}
+	var is struct {
+		Fields struct {
+			Comment struct {
+				Comments []json.RawMessage
+			}
+		}
+	}
+	if err := json.Unmarshal(raw, &is); err != nil {
+		return fmt.Errorf("decode issue: %w", err)
+	}
+	for _, c := range is.Fields.Comment.Comments {
+		var comment struct{ ID string }
+		if err := json.Unmarshal(c, &comment); err != nil {
+			return fmt.Errorf("decode comment: %w", err)
+		}
+		if comment.ID == "" || strings.ContainsAny(comment.ID, `/\`) {
+			return fmt.Errorf("bad comment id %q", comment.ID)
+		}
+		if err := writeFile(filepath.Join(s.dir, "comment", comment.ID), c); err != nil {
+			return err
+		}
+	}
+	// Write the issue last, so an interrupted sync fetches it again.
+	return writeFile(name, raw)
+}
+
+// fields mirrors the list of fields, as used by Client.Fields.
+func (s *syncer) fields() error {
+	u := *s.client.APIRoot
+	u.Path = path.Join(u.Path, "field")
+	raw, err := s.client.getJSON(s.ctx, u.String())
+	if err != nil {
+		return fmt.Errorf("mirror fields: %w", err)
+	}
+	return writeFile(filepath.Join(s.dir, "field"), raw)
+}
+
+// project mirrors the project with the given key,
+// once per syncer.
+func (s *syncer) project(key string) error {
+	if key == "" || s.projects[key] {
+		return nil
+	}
+	u := *s.client.APIRoot
+	u.Path = path.Join(u.Path, "project", key)
+	raw, err := s.client.getJSON(s.ctx, u.String())
+	if err != nil {
+		return fmt.Errorf("mirror project %s: %w", key, err)
+	}
+	if err := writeFile(filepath.Join(s.dir, "project", key), raw); err != nil {
+		return err
+	}
+	if s.projects == nil {
+		s.projects = make(map[string]bool)
+	}
+	s.projects[key] = true
+	return nil
+}
+
+// MirrorClient returns a Client which reads from the mirror in dir
+// instead of a Jira server, so that an FS or other programs
+// using the Client work offline.
+//
+// A mirror is a directory, written by SyncProject and SyncQuery,
+// holding projects, issues and comments as JSON returned by Jira:
+//
+//	field	the list of fields
+//	project/KEY	the project with key KEY
+//	issue/KEY-N	the issue KEY-N, including its comments
+//	comment/ID	the comment with ID
+//
+// The mirror is read-only: requests to change anything fail.
+// Searches are evaluated locally by Query.Match,
+// so only queries accepted by Query.Check are supported.
+func MirrorClient(dir string) (*Client, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(filepath.Join(dir, "issue")); err != nil {
+		return nil, fmt.Errorf("open mirror: %w", err)
+	}
+	root := &url.URL{Scheme: "file", Host: "localhost", Path: filepath.ToSlash(dir)}
+	t := &mirror{fsys: os.DirFS(dir), prefix: root.Path}
+	return &Client{Client: &http.Client{Transport: t}, APIRoot: root}, nil
+}
+
+// mirror answers API requests from the files of a mirror,
+// responding as Jira would to the subset of requests made
+// when reading projects, issues, comments and fields.
+type mirror struct {
+	fsys fs.FS
+	// prefix is the path of the API root, removed from request paths.
+	prefix string
+}
+
+func (m *mirror) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return mirrorError(req, http.StatusMethodNotAllowed, "mirror is read-only"), nil
+	}
+	p := strings.Trim(strings.TrimPrefix(req.URL.Path, m.prefix), "/")
+	elems := strings.Split(p, "/")
+	switch {
+	case p == "project":
+		return m.list(req, "project")
+	case p == "search":
+		return m.search(req)
+	case p == "field":
+		return m.file(req, "field")
+	case len(elems) == 2 && elems[0] == "project":
+		return m.file(req, p)
+	case len(elems) == 2 && elems[0] == "issue":
+		// The mirrored issue holds every field,
+		// so serves requests for only some of them too.
+		return m.file(req, p)
+	case len(elems) == 4 && elems[0] == "issue" && elems[2] == "comment":
+		return m.file(req, path.Join("comment", elems[3]))
+	}
+	return mirrorError(req, http.StatusNotFound, "not in mirror: "+p), nil
+}
+
+// file responds with the contents of the named file in the mirror.
+func (m *mirror) file(req *http.Request, name string) (*http.Response, error) {
+	if !fs.ValidPath(name) {
+		return mirrorError(req, http.StatusNotFound, "not in mirror: "+name), nil
+	}
+	b, err := fs.ReadFile(m.fsys, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return mirrorError(req, http.StatusNotFound, "not in mirror: "+name), nil
+	} else if err != nil {
+		return nil, err
+	}
+	return mirrorResponse(req, http.StatusOK, b), nil
+}
+
+// readDir returns the contents of each file in the named directory
+// of the mirror, or nothing if there is no such directory.
+func (m *mirror) readDir(dir string) ([]json.RawMessage, error) {
+	dents, err := fs.ReadDir(m.fsys, dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	files := make([]json.RawMessage, 0, len(dents))
+	for _, d := range dents {
+		b, err := fs.ReadFile(m.fsys, path.Join(dir, d.Name()))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, b)
+	}
+	return files, nil
+}
+
+// list responds with a JSON array of the files in dir.
+func (m *mirror) list(req *http.Request, dir string) (*http.Response, error) {
+	files, err := m.readDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	if files == nil {
+		files = []json.RawMessage{}
+	}
+	b, err := json.Marshal(files)
+	if err != nil {
+		return nil, err
+	}
+	return mirrorResponse(req, http.StatusOK, b), nil
+}
+
+// search responds with the page of mirrored issues
+// matching the request's JQL query.
+func (m *mirror) search(req *http.Request) (*http.Response, error) {
+	params := req.URL.Query()
+	q, err := ParseQuery(params.Get("jql"))
+	if err == nil {
+		err = q.Check()
+	}
+	if err != nil {
+		return mirrorError(req, http.StatusBadRequest, "Error in the JQL Query: "+err.Error()), nil
+	}
+	startAt, _ := strconv.Atoi(params.Get("startAt"))
+	maxResults, err := strconv.Atoi(params.Get("maxResults"))
+	if err != nil {
+		maxResults = searchPageSize
+	}
+	startAt, maxResults = max(startAt, 0), max(maxResults, 0)
+
+	files, err := m.readDir("issue")
+	if err != nil {
+		return nil, err
+	}
+	type hit struct {
+		raw   json.RawMessage
+		issue Issue
+	}
+	var matched []hit
+	for _, b := range files {
+		var is Issue
+		if err := json.Unmarshal(b, &is); err != nil {
+			return nil, fmt.Errorf("decode mirrored issue: %w", err)
+		}
+		if q.Match(&is, &QueryEnv{}) {
+			matched = append(matched, hit{b, is})
+		}
+	}
+	slices.SortStableFunc(matched, func(a, b hit) int {
+		return q.Compare(&a.issue, &b.issue)
+	})
+	page := matched[min(startAt, len(matched)):]
+	page = page[:min(maxResults, len(page))]
+	res := struct {
+		StartAt    int               `json:"startAt"`
+		MaxResults int               `json:"maxResults"`
+		Total      int               `json:"total"`
+		Issues     []json.RawMessage `json:"issues"`
+	}{startAt, maxResults, len(matched), make([]json.RawMessage, len(page))}
+	for i := range page {
+		res.Issues[i] = page[i].raw
+	}
+	b, err := json.Marshal(res)
+	if err != nil {
+		return nil, err
+	}
+	return mirrorResponse(req, http.StatusOK, b), nil
+}
+
+// mirrorError returns a response with status
+// and a Jira error body holding msg.
+func mirrorError(req *http.Request, status int, msg string) *http.Response {
+	b, _ := json.Marshal(map[string]any{"errorMessages": []string{msg}, "errors": map[string]string{}})
+	return mirrorResponse(req, status, b)
+}
+
+func mirrorResponse(req *http.Request, status int, body []byte) *http.Response {
+	if req.Method == http.MethodHead {
+		body = nil
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
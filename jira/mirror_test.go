@@ -0,0 +1,91 @@
+package jira_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"olowe.co/issues/jira"
+)
+
+func TestMirror(t *testing.T) {
+	srv, client := newServer(t)
+	rec := record(client)
+	if err := client.Transition("TEST-1", "Done"); err != nil {
+		t.Fatal(err)
+	}
+	created, err := client.CreateIssue(&jira.Issue{Project: jira.Project{Key: "TEST"}, Summary: "hello"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	ctx := context.Background()
+	if err := jira.SyncProject(ctx, client, dir, "TEST"); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"field", "project/TEST", "issue/TEST-1", "issue/" + created.Key} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("not mirrored: %v", err)
+		}
+	}
+
+	mirror, err := jira.MirrorClient(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	browse(t, &jira.FS{Client: mirror})
+	issues, err := mirror.Issues("TEST")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 2 {
+		t.Errorf("got %d issues in mirrored project, want 2", len(issues))
+	}
+	if issues, err := mirror.Issues("NOPE"); err != nil || len(issues) != 0 {
+		t.Errorf("search other project: got %d issues, error %v", len(issues), err)
+	}
+	open, err := mirror.SearchIssues("project = TEST and status = Open")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(open) != 1 || open[0].Key != created.Key {
+		t.Errorf("search for open issues: got %v, want only %s", open, created.Key)
+	}
+	if _, err := mirror.SearchIssues("status was Open"); err == nil {
+		t.Error("query needing history succeeded")
+	}
+	err = mirror.UpdateIssue("TEST-1", map[string]any{"summary": "changed"})
+	var jerr *jira.Error
+	if !errors.As(err, &jerr) || jerr.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("want method not allowed error editing mirror, got %v", err)
+	}
+	if _, err := mirror.Issue("TEST-999"); !errors.Is(err, jira.ErrNotFound) {
+		t.Errorf("want ErrNotFound for missing issue, got %v", err)
+	}
+
+	if _, err := mirror.Fields(); err != nil {
+		t.Errorf("get mirrored fields: %v", err)
+	}
+
+	// Unchanged issues are not fetched again:
+	// only the fields, search and project are requested.
+	rec.take()
+	if err := jira.SyncProject(ctx, client, dir, "TEST"); err != nil {
+		t.Fatal(err)
+	}
+	if n := rec.count(); n != 3 {
+		t.Errorf("resync of unchanged project made %d requests, want 3", n)
+	}
+	// Deleted issues are removed.
+	srv.DeleteIssue(created.Key)
+	if err := jira.SyncProject(ctx, client, dir, "TEST"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "issue", created.Key)); !os.IsNotExist(err) {
+		t.Errorf("deleted issue %s still mirrored", created.Key)
+	}
+}
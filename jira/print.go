@@ -4,8 +4,11 @@ import (
 	"fmt"
 	"net/url"
 	"path"
+	"slices"
 	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
 func printIssues(issues []Issue) string {
@@ -17,7 +20,11 @@ func printIssues(issues []Issue) string {
 	return buf.String()
 }
 
-func printIssue(i *Issue) string {
+// printIssue returns the textual representation of an issue.
+// Unless raw is true, the description is converted from Jira text formatting
+// to plain text.
+// Each of fields is shown as a header, even if the issue has no value for it.
+func printIssue(i *Issue, raw bool, fields []Field) string {
 	buf := &strings.Builder{}
 	fmt.Fprintln(buf, "From:", i.Reporter)
 	fmt.Fprintln(buf, "Date:", i.Created.Format(time.RFC1123Z))
@@ -30,13 +37,41 @@ func printIssue(i *Issue) string {
 	}
 	fmt.Fprintf(buf, "Archived-At: <%s>\n", i.URL)
 	fmt.Fprintln(buf, "Status:", i.Status.Name)
-	if len(i.Links) > 0 {
-		s := make([]string, len(i.Links))
-		for j := range i.Links {
-			s[j] = i.Links[j].Key
+	if i.Resolution.Name != "" {
+		fmt.Fprintln(buf, "Resolution:", i.Resolution.Name)
+	}
+	if i.Type.Name != "" {
+		fmt.Fprintln(buf, "Type:", i.Type.Name)
+	}
+	if i.Priority.Name != "" {
+		fmt.Fprintln(buf, "Priority:", i.Priority.Name)
+	}
+	if len(i.Labels) > 0 {
+		fmt.Fprintln(buf, "Labels:", strings.Join(i.Labels, ", "))
+	}
+	if len(i.Components) > 0 {
+		s := make([]string, len(i.Components))
+		for j := range i.Components {
+			s[j] = i.Components[j].Name
 		}
-		fmt.Fprintln(buf, "References:", strings.Join(s, ", "))
+		fmt.Fprintln(buf, "Components:", strings.Join(s, ", "))
+	}
+	if len(i.FixVersions) > 0 {
+		fmt.Fprintln(buf, "Fix-Versions:", versionList(i.FixVersions))
+	}
+	if len(i.AffectsVersions) > 0 {
+		fmt.Fprintln(buf, "Affects-Versions:", versionList(i.AffectsVersions))
 	}
+	if !i.Due.IsZero() {
+		fmt.Fprintln(buf, "Due:", i.Due.Format(time.DateOnly))
+	}
+	if i.Parent != nil {
+		fmt.Fprintln(buf, "Parent:", i.Parent.Key)
+	}
+	if i.Watchers > 0 {
+		fmt.Fprintln(buf, "Watchers:", i.Watchers)
+	}
+	buf.WriteString(printLinks(i.Links))
 	if len(i.Subtasks) > 0 {
 		s := make([]string, len(i.Subtasks))
 		for j := range i.Subtasks {
@@ -44,17 +79,70 @@ func printIssue(i *Issue) string {
 		}
 		fmt.Fprintln(buf, "Subtasks:", strings.Join(s, ", "))
 	}
+	for _, f := range fields {
+		fmt.Fprintf(buf, "%s: %s\n", headerName(f), formatField(f, i.Extra[f.ID]))
+	}
 	fmt.Fprintln(buf, "Subject:", i.Summary)
 	fmt.Fprintln(buf)
 
 	if i.Description != "" {
-		fmt.Fprintln(buf, strings.ReplaceAll(i.Description, "\r", ""))
+		fmt.Fprintln(buf, printBody(i.Description, raw))
 	}
 	if len(i.Comments) == 0 {
 		return buf.String()
 	}
 	fmt.Fprintln(buf)
-	for _, c := range i.Comments {
+	buf.WriteString(printCommentList(i.Comments))
+	return buf.String()
+}
+
+// printLinks returns a header for each relation of links,
+// such as "Blocks: TEST-4, TEST-5", in the order the relations first appear.
+func printLinks(links []Link) string {
+	var relations []string
+	keys := make(map[string][]string)
+	for _, l := range links {
+		r := l.Relation()
+		if linkHeader(r) == "" {
+			continue
+		}
+		if _, ok := keys[r]; !ok {
+			relations = append(relations, r)
+		}
+		keys[r] = append(keys[r], l.Issue.Key)
+	}
+	buf := &strings.Builder{}
+	for _, r := range relations {
+		fmt.Fprintf(buf, "%s: %s\n", linkHeader(r), strings.Join(keys[r], ", "))
+	}
+	return buf.String()
+}
+
+// linkHeader returns the name of the header listing issues
+// linked by relation, such as "Is-Blocked-By" for "is blocked by",
+// or an empty string if relation has nothing to name a header by.
+func linkHeader(relation string) string {
+	words := strings.Fields(relation)
+	for i, w := range words {
+		r, n := utf8.DecodeRuneInString(w)
+		words[i] = string(unicode.ToUpper(r)) + w[n:]
+	}
+	return headerName(Field{Name: strings.Join(words, " ")})
+}
+
+// versionList returns the names of versions separated by commas.
+func versionList(versions []Version) string {
+	s := make([]string, len(versions))
+	for i := range versions {
+		s[i] = versions[i].Name
+	}
+	return strings.Join(s, ", ")
+}
+
+// printCommentList returns a one-line digest of each comment.
+func printCommentList(comments []Comment) string {
+	buf := &strings.Builder{}
+	for _, c := range comments {
 		date := c.Created
 		if !c.Updated.IsZero() {
 			date = c.Updated
@@ -64,7 +152,7 @@ func printIssue(i *Issue) string {
 	return buf.String()
 }
 
-func printComment(c *Comment) string {
+func printComment(c *Comment, raw bool) string {
 	buf := &strings.Builder{}
 	date := c.Created
 	if !c.Updated.IsZero() {
@@ -73,10 +161,57 @@ func printComment(c *Comment) string {
 	fmt.Fprintln(buf, "From:", c.Author)
 	fmt.Fprintln(buf, "Date:", date.Format(time.RFC1123Z))
 	fmt.Fprintln(buf)
-	fmt.Fprintln(buf, strings.TrimSpace(c.Body))
+	fmt.Fprintln(buf, printBody(c.Body, raw))
 	return buf.String()
 }
 
+// printThread returns the issue's description followed by
+// the full text of every comment in the order they were written.
+func printThread(i *Issue, raw bool) string {
+	buf := &strings.Builder{}
+	fmt.Fprintln(buf, "Subject:", i.Summary)
+	fmt.Fprintln(buf, "Status:", i.Status.Name)
+	fmt.Fprintf(buf, "\nReported by %s (%s)\n", i.Reporter, i.Created.Format(time.DateTime))
+	if i.Description != "" {
+		fmt.Fprintf(buf, "\n%s\n", indent(printBody(i.Description, raw)))
+	}
+
+	comments := slices.Clone(i.Comments)
+	slices.SortStableFunc(comments, func(a, b Comment) int {
+		return a.Created.Compare(b.Created)
+	})
+	for _, c := range comments {
+		fmt.Fprintf(buf, "\nComment %s by %s (%s)", c.ID, c.Author, c.Created.Format(time.DateTime))
+		if c.Updated.After(c.Created) {
+			fmt.Fprintf(buf, ", edited %s", c.Updated.Format(time.DateTime))
+		}
+		fmt.Fprintln(buf)
+		if body := printBody(c.Body, raw); body != "" {
+			fmt.Fprintf(buf, "\n%s\n", indent(body))
+		}
+	}
+	return buf.String()
+}
+
+// indent prefixes each non-blank line of s with a tab.
+func indent(s string) string {
+	lines := strings.Split(s, "\n")
+	for i := range lines {
+		if lines[i] != "" {
+			lines[i] = "\t" + lines[i]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// printBody returns the text of a description or comment body.
+func printBody(jtf string, raw bool) string {
+	if raw {
+		return strings.Trim(strings.ReplaceAll(jtf, "\r", ""), "\n")
+	}
+	return FromJTF(jtf)
+}
+
 func summarise(body string, length int) string {
 	if len(body) < length {
 		body = strings.ReplaceAll(body, "\n", " ")
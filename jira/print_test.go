@@ -0,0 +1,183 @@
+package jira
+
+import (
+	"encoding/json"
+	"net/mail"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrintThread(t *testing.T) {
+	created := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	issue := &Issue{
+		Key:         "TEST-1",
+		Summary:     "Printer on fire",
+		Reporter:    User{DisplayName: "Fred", Email: "fred@example.com"},
+		Description: "It is *very* hot.",
+		Created:     created,
+		Comments: []Comment{
+			{ID: "2", Body: "Put it out!", Author: User{DisplayName: "Ann"}, Created: created.Add(2 * time.Hour)},
+			{ID: "1", Body: "h3. Update\nStill burning.", Author: User{DisplayName: "Bob"}, Created: created.Add(time.Hour)},
+		},
+	}
+	got := printThread(issue, false)
+	for _, want := range []string{
+		"Reported by Fred <fred@example.com> (2024-01-01 09:00:00)\n\n\tIt is *very* hot.\n",
+		"Comment 1 by Bob (2024-01-01 10:00:00)\n\n\t# Update\n\n\tStill burning.\n",
+		"Comment 2 by Ann (2024-01-01 11:00:00)\n\n\tPut it out!\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("thread missing %q", want)
+		}
+	}
+	if strings.Index(got, "Comment 1") > strings.Index(got, "Comment 2") {
+		t.Errorf("comments not in chronological order")
+	}
+	if t.Failed() {
+		t.Log(got)
+	}
+}
+
+func TestPrintIssueHeaders(t *testing.T) {
+	blocks := LinkType{Name: "Blocks", Inward: "is blocked by", Outward: "blocks"}
+	issue := &Issue{
+		Key:             "TEST-2",
+		Summary:         "Out of paper",
+		Status:          Status{"Done"},
+		Resolution:      Resolution{Name: "Fixed"},
+		Type:            IssueType{Name: "Bug"},
+		Priority:        Priority{Name: "High"},
+		Labels:          []string{"printer", "office"},
+		Components:      []Component{{Name: "Hardware"}},
+		FixVersions:     []Version{{Name: "1.1"}, {Name: "2.0"}},
+		AffectsVersions: []Version{{Name: "1.0"}},
+		Due:             time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		Parent:          &Issue{Key: "TEST-1"},
+		Watchers:        3,
+		Links: []Link{
+			{Type: blocks, Outward: true, Issue: Issue{Key: "TEST-4"}},
+			{Type: blocks, Issue: Issue{Key: "TEST-1"}},
+			{Type: blocks, Outward: true, Issue: Issue{Key: "TEST-5"}},
+		},
+		Subtasks: []Issue{{Key: "TEST-6"}, {Key: "TEST-7"}},
+	}
+	got := printIssue(issue, false, nil)
+	for _, want := range []string{
+		"Status: Done\n",
+		"Resolution: Fixed\n",
+		"Type: Bug\n",
+		"Priority: High\n",
+		"Labels: printer, office\n",
+		"Components: Hardware\n",
+		"Fix-Versions: 1.1, 2.0\n",
+		"Affects-Versions: 1.0\n",
+		"Due: 2024-03-01\n",
+		"Parent: TEST-1\n",
+		"Watchers: 3\n",
+		"Blocks: TEST-4, TEST-5\n",
+		"Is-Blocked-By: TEST-1\n",
+		"Subtasks: TEST-6, TEST-7\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("issue missing header %q", want)
+		}
+	}
+	if t.Failed() {
+		t.Log(got)
+	}
+
+	// Unset fields are left out.
+	got = printIssue(&Issue{Key: "TEST-3", Summary: "hello"}, false, nil)
+	for _, h := range []string{"Resolution:", "Priority:", "Due:", "Parent:", "Watchers:"} {
+		if strings.Contains(got, h) {
+			t.Errorf("issue without %s has header:\n%s", h, got)
+		}
+	}
+}
+
+func TestIssueChangesUnmodified(t *testing.T) {
+	b, err := os.ReadFile("testdata/issue/TEST-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var issue Issue
+	if err := json.Unmarshal(b, &issue); err != nil {
+		t.Fatal(err)
+	}
+	for _, raw := range []bool{false, true} {
+		msg, err := mail.ReadMessage(strings.NewReader(printIssue(&issue, raw, nil)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		fields, status, err := issueChanges(&issue, msg, raw, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(fields) > 0 {
+			t.Errorf("unmodified issue (raw %v) has changed fields: %v", raw, fields)
+		}
+		if status != "" {
+			t.Errorf("unmodified issue (raw %v) has changed status %q", raw, status)
+		}
+	}
+}
+
+func TestPrintHistory(t *testing.T) {
+	b, err := os.ReadFile("testdata/changelog/TEST-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var changes []Change
+	if err := json.Unmarshal(b, &changes); err != nil {
+		t.Fatal(err)
+	}
+	want := `* Fred Smith <fred@example.com> changed title (2002-02-08 05:20:00)
+  - Time zones
+  + User Preference: User Time Zones
+
+* Fred Smith <fred@example.com> assigned Ann Jones (2002-02-08 05:20:00)
+
+* Ann Jones <ann@example.com> labeled affects-cloud (2002-03-01 10:00:00)
+
+* Ann Jones <ann@example.com> changed Story Points (2002-03-01 10:00:00)
+  + 3
+
+* Ann Jones <ann@example.com> changed status (2002-03-02 11:30:00)
+  - Open
+  + Closed
+
+* Ann Jones <ann@example.com> unassigned Ann Jones (2002-03-02 11:30:00)
+`
+	if got := printHistory(changes); got != want {
+		t.Errorf("history:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestLinkHeader(t *testing.T) {
+	for relation, want := range map[string]string{
+		"is blocked by": "Is-Blocked-By",
+		"relates to":    "Relates-To",
+		"étend":         "tend",
+		"über alles":    "ber-Alles",
+		"":              "",
+		" ":             "",
+	} {
+		if got := linkHeader(relation); got != want {
+			t.Errorf("header for %q: got %q, want %q", relation, got, want)
+		}
+	}
+}
+
+func TestPrintLinks(t *testing.T) {
+	links := []Link{
+		{Type: LinkType{Name: "Blocks", Inward: "is blocked by", Outward: "blocks"}, Issue: Issue{Key: "TEST-2"}},
+		{Type: LinkType{Name: "Follows"}, Outward: true, Issue: Issue{Key: "TEST-3"}},
+		{Type: LinkType{}, Issue: Issue{Key: "TEST-4"}},
+	}
+	want := "Is-Blocked-By: TEST-2\nFollows: TEST-3\n"
+	if got := printLinks(links); got != want {
+		t.Errorf("got links %q, want %q", got, want)
+	}
+}
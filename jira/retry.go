@@ -0,0 +1,171 @@
+package jira
+
+import (
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy controls how a Client retries failed requests.
+//
+// Requests rejected with status 429 Too Many Requests are always retried,
+// as the server did not act on them.
+// Requests with idempotent methods (GET, HEAD, PUT and DELETE)
+// are also retried after a 5xx status or a reset connection.
+type RetryPolicy struct {
+	// MaxRetries is the most times a request is retried.
+	MaxRetries int
+	// MinBackoff is the delay before the first retry.
+	// The delay doubles with each further retry, up to MaxBackoff.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// MaxWait is the longest the client will wait
+	// when the server asks for a delay using Retry-After.
+	// If the server asks for longer, its response is returned instead.
+	MaxWait time.Duration
+}
+
+// DefaultRetryPolicy is a reasonable RetryPolicy for interactive
+// and batch programs alike.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries: 4,
+	MinBackoff: 500 * time.Millisecond,
+	MaxBackoff: 30 * time.Second,
+	MaxWait:    2 * time.Minute,
+}
+
+// backoff returns the delay before retry number n, counting from 0.
+// A random jitter of up to half the delay spreads out retries
+// from many clients failing at once.
+func (p *RetryPolicy) backoff(n int) time.Duration {
+	d := p.MinBackoff << n
+	if d > p.MaxBackoff || d <= 0 {
+		d = p.MaxBackoff
+	}
+	if d < 2 {
+		return d
+	}
+	return d/2 + rand.N(d/2)
+}
+
+// retryDelay reports whether a request should be retried
+// after receiving resp or err on attempt n, and how long to wait first.
+func (p *RetryPolicy) retryDelay(req *http.Request, resp *http.Response, err error, n int) (time.Duration, bool) {
+	if n >= p.MaxRetries {
+		return 0, false
+	}
+	if err != nil {
+		if req.Context().Err() != nil {
+			return 0, false
+		}
+		if idempotent(req.Method) && resetConn(err) {
+			return p.backoff(n), true
+		}
+		return 0, false
+	}
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+	case resp.StatusCode >= http.StatusInternalServerError && idempotent(req.Method):
+	default:
+		return 0, false
+	}
+	if wait, ok := retryAfter(resp.Header, time.Now()); ok {
+		if wait > p.MaxWait {
+			return 0, false
+		}
+		return wait, true
+	}
+	return p.backoff(n), true
+}
+
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// resetConn reports whether err shows the connection to the server
+// was lost before a response was read.
+func resetConn(err error) bool {
+	return errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNABORTED) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, io.EOF)
+}
+
+// retryAfter returns the delay requested by the Retry-After header field,
+// given as either a number of seconds or an HTTP date.
+func retryAfter(header http.Header, now time.Time) (time.Duration, bool) {
+	v := header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(t.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// RateLimit describes the rate limiting reported by the server
+// in its most recent response.
+// Fields not reported by the server are left as their zero value.
+type RateLimit struct {
+	// Limit is the number of requests allowed in the current period,
+	// and Remaining how many of those are left.
+	Limit     int
+	Remaining int
+	// Reset is when the limit is next replenished.
+	Reset time.Time
+	// NearLimit is set when the server warns that few requests remain.
+	NearLimit bool
+	// Throttled is set when the most recent request was rejected
+	// with status 429 Too Many Requests;
+	// RetryAfter is when the server asked the client to try again.
+	Throttled  bool
+	RetryAfter time.Time
+}
+
+// parseRateLimit returns the rate limit state reported in resp
+// by the X-RateLimit header fields used by Jira.
+func parseRateLimit(resp *http.Response, now time.Time) RateLimit {
+	var rl RateLimit
+	h := resp.Header
+	rl.Limit, _ = strconv.Atoi(h.Get("X-RateLimit-Limit"))
+	rl.Remaining, _ = strconv.Atoi(h.Get("X-RateLimit-Remaining"))
+	if v := h.Get("X-RateLimit-Reset"); v != "" {
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			rl.Reset = t
+		}
+	}
+	rl.NearLimit, _ = strconv.ParseBool(h.Get("X-RateLimit-NearLimit"))
+	if resp.StatusCode == http.StatusTooManyRequests {
+		rl.Throttled = true
+		if wait, ok := retryAfter(h, now); ok {
+			rl.RetryAfter = now.Add(wait)
+		}
+	}
+	return rl
+}
+
+// RateLimit returns the rate limit state reported by the server
+// in the most recent response received by the client.
+func (c *Client) RateLimit() RateLimit {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.rateLimit
+}
+
+func (c *Client) setRateLimit(rl RateLimit) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rateLimit = rl
+}
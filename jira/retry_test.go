@@ -0,0 +1,169 @@
+package jira
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+var testRetryPolicy = RetryPolicy{
+	MaxRetries: 3,
+	MinBackoff: time.Millisecond,
+	MaxBackoff: 10 * time.Millisecond,
+	MaxWait:    5 * time.Second,
+}
+
+// fault is a failure served by faultyHandler.
+// A zero status closes the connection without responding.
+type fault struct {
+	status     int
+	retryAfter string
+}
+
+// faultyHandler serves each of its faults in turn,
+// one per request, then serves the issue in testdata
+// or, for POST requests, reports an issue as created.
+// Throttled responses carry the rate limit headers sent by Jira Cloud.
+type faultyHandler struct {
+	mu       sync.Mutex
+	faults   []fault
+	requests int
+}
+
+func (h *faultyHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	h.mu.Lock()
+	h.requests++
+	if len(h.faults) == 0 {
+		h.mu.Unlock()
+		if req.Method == http.MethodPost {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id": "10001", "key": "TEST-2"}`))
+			return
+		}
+		http.ServeFile(w, req, "testdata/issue/TEST-1")
+		return
+	}
+	f := h.faults[0]
+	h.faults = h.faults[1:]
+	h.mu.Unlock()
+
+	if f.status == 0 {
+		conn, _, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			log.Println("hijack connection:", err)
+			return
+		}
+		conn.Close()
+		return
+	}
+	if f.retryAfter != "" {
+		w.Header().Set("Retry-After", f.retryAfter)
+	}
+	if f.status == http.StatusTooManyRequests {
+		w.Header().Set("X-RateLimit-Limit", "100")
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-NearLimit", "true")
+	}
+	http.Error(w, http.StatusText(f.status), f.status)
+}
+
+func newFaultyServer(t *testing.T) (*faultyHandler, *Client) {
+	t.Helper()
+	h := &faultyHandler{}
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy := testRetryPolicy
+	return h, &Client{APIRoot: u, Retry: &policy}
+}
+
+func TestRetry(t *testing.T) {
+	h, client := newFaultyServer(t)
+	h.faults = []fault{
+		{status: http.StatusTooManyRequests, retryAfter: "0"},
+		{status: http.StatusServiceUnavailable},
+		{status: 0}, // connection reset
+	}
+	if _, err := client.Issue("TEST-1"); err != nil {
+		t.Fatalf("get issue after transient failures: %v", err)
+	}
+	if h.requests != 4 {
+		t.Errorf("server got %d requests, want 4", h.requests)
+	}
+	if rl := client.RateLimit(); rl.Throttled {
+		t.Errorf("client reports throttling after successful request: %+v", rl)
+	}
+
+	// POST is not idempotent, so server errors are not retried...
+	h.requests = 0
+	h.faults = []fault{{status: http.StatusServiceUnavailable}}
+	issue := &Issue{Project: Project{Key: "TEST"}, Summary: "hello"}
+	_, err := client.CreateIssue(issue)
+	var jerr *Error
+	if !errors.As(err, &jerr) || jerr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("want error with status %d, got %v", http.StatusServiceUnavailable, err)
+	}
+	if h.requests != 1 {
+		t.Errorf("non-idempotent request sent %d times, want 1", h.requests)
+	}
+	// ...but throttled requests were never acted on, so they are.
+	h.faults = []fault{{status: http.StatusTooManyRequests, retryAfter: "0"}}
+	if _, err := client.CreateIssue(issue); err != nil {
+		t.Errorf("create issue after throttling: %v", err)
+	}
+}
+
+func TestRetryExhausted(t *testing.T) {
+	h, client := newFaultyServer(t)
+	for i := 0; i <= testRetryPolicy.MaxRetries; i++ {
+		h.faults = append(h.faults, fault{status: http.StatusTooManyRequests, retryAfter: "0"})
+	}
+	_, err := client.Issue("TEST-1")
+	if !errors.Is(err, ErrRateLimited) {
+		t.Errorf("want ErrRateLimited, got %v", err)
+	}
+	if h.requests != testRetryPolicy.MaxRetries+1 {
+		t.Errorf("server got %d requests, want %d", h.requests, testRetryPolicy.MaxRetries+1)
+	}
+	rl := client.RateLimit()
+	if !rl.Throttled || !rl.NearLimit || rl.Limit != 100 || rl.Remaining != 0 {
+		t.Errorf("unexpected rate limit state %+v", rl)
+	}
+
+	// Don't wait longer than MaxWait.
+	h.requests = 0
+	h.faults = []fault{{status: http.StatusTooManyRequests, retryAfter: "3600"}}
+	if _, err := client.Issue("TEST-1"); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("want ErrRateLimited, got %v", err)
+	}
+	if h.requests != 1 {
+		t.Errorf("retried request despite long Retry-After")
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	h, client := newFaultyServer(t)
+	h.faults = []fault{{status: http.StatusTooManyRequests, retryAfter: "1"}}
+	start := time.Now()
+	if _, err := client.Issue("TEST-1"); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("retried after %s, want at least 1s as requested by Retry-After", elapsed)
+	}
+
+	now := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	header := http.Header{"Retry-After": {"Mon, 01 Jan 2024 09:00:30 GMT"}}
+	if d, ok := retryAfter(header, now); !ok || d != 30*time.Second {
+		t.Errorf("retry after %s, want 30s", d)
+	}
+}